@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"os"
 	"path"
 
 	"github.com/layer5io/meshery-adapter-library/common"
@@ -13,10 +15,41 @@ import (
 
 const (
 	LinkerdOperation = "linkerd"
+	LinkerdCNI       = "linkerd-cni"
 	Development      = "development"
 	Production       = "production"
 
-	AnnotateNamespace = "annotate-namespace"
+	AnnotateNamespace   = "annotate-namespace"
+	RotateIssuer        = "rotate-issuer"
+	RotateTrustAnchor   = "rotate-trust-anchor"
+	MulticlusterLink    = "multicluster-link"
+	ExportServices      = "export-services"
+	InjectWorkloads     = "inject-workloads"
+	MeshStatus          = "mesh-status"
+	UpgradeProxies      = "upgrade-proxies"
+	ProxyConfig         = "proxy-config"
+	ServiceProfile      = "service-profile"
+	RoutePolicy         = "route-policy"
+	Canary              = "canary"
+	FaultInjection      = "fault-injection"
+	AuthorizationPolicy = "authorization-policy"
+	DefaultDenyPolicy   = "default-deny-policy"
+	MTLSVerify          = "mtls-verify"
+	Stats               = "stats"
+
+	// ClustersKey is the config key holding the mesh status of every cluster
+	ClustersKey = "clusters"
+
+	// KubeconfigSourceKey is the config key holding the source of the kubernetes credentials in use
+	KubeconfigSourceKey = "kubeconfig-source"
+
+	// CertificateExpiry is the mesh spec key holding the next identity certificate expiry
+	CertificateExpiry = "certificate-expiry"
+
+	// Environment variables overriding the certificate expiry watcher defaults
+	CertWatchNamespaceEnv  = "CERT_WATCH_NAMESPACE"
+	CertWatchIntervalEnv   = "CERT_WATCH_INTERVAL"
+	CertWatchThresholdsEnv = "CERT_WATCH_THRESHOLDS"
 )
 
 var (
@@ -40,6 +73,8 @@ var (
 		"name":    smp.ServiceMesh_LINKERD.Enum().String(),
 		"status":  status.NotInstalled,
 		"version": status.None,
+
+		CertificateExpiry: status.None,
 	}
 
 	ProviderConfig = map[string]string{
@@ -55,6 +90,14 @@ var (
 		configprovider.FileName: "kubeconfig",
 	}
 
+	// CertificateWatch holds the defaults of the certificate expiry watcher,
+	// thresholds are a comma separated list of durations before expiry
+	CertificateWatch = map[string]string{
+		CertWatchNamespaceEnv:  "linkerd",
+		CertWatchIntervalEnv:   "1h",
+		CertWatchThresholdsEnv: "720h,168h,24h",
+	}
+
 	Operations = getOperations(common.Operations)
 )
 
@@ -85,6 +128,27 @@ func NewKubeconfigBuilder(provider string) (config.Handler, error) {
 	return nil, ErrEmptyConfig
 }
 
+// GetCertificateWatch returns the settings of the certificate expiry watcher
+// with the defaults overridden by the environment
+func GetCertificateWatch() map[string]string {
+	settings := make(map[string]string, len(CertificateWatch))
+	for key, value := range CertificateWatch {
+		settings[key] = value
+		if env := os.Getenv(key); env != "" {
+			settings[key] = env
+		}
+	}
+	return settings
+}
+
+// KubeConfigPath returns the location of the kubeconfig file managed by the adapter
+func KubeConfigPath() string {
+	return path.Join(
+		KubeConfig[configprovider.FilePath],
+		fmt.Sprintf("%s.%s", KubeConfig[configprovider.FileName], KubeConfig[configprovider.FileType]),
+	)
+}
+
 // RootPath returns the config root path for the adapter
 func RootPath() string {
 	return configRootPath
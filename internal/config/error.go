@@ -22,10 +22,13 @@ const (
 	ErrEmptyConfigCode           = "1000"
 	ErrGetLatestReleasesCode     = "1001"
 	ErrGetLatestReleaseNamesCode = "1002"
+	ErrNoKubeconfigCode          = "1031"
 )
 
 var (
 	ErrEmptyConfig = errors.New(ErrEmptyConfigCode, errors.Alert, []string{"Config is empty"}, []string{}, []string{}, []string{})
+
+	ErrNoKubeconfig = errors.New(ErrNoKubeconfigCode, errors.Alert, []string{"No Kubernetes credentials found"}, []string{"Neither the kubeconfig managed by the adapter nor $KUBECONFIG contain a context and the adapter is not running inside a cluster"}, []string{}, []string{"Upload a kubeconfig from Meshery, set $KUBECONFIG or deploy the adapter with the service account in install/kubernetes/rbac.yaml"})
 )
 
 // ErrGetLatestReleases is the error for fetching linkerd releases
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/layer5io/meshery-adapter-library/adapter"
+)
+
+const (
+	LinkerdViz          = "linkerd-viz"
+	LinkerdJaeger       = "linkerd-jaeger"
+	LinkerdMulticluster = "linkerd-multicluster"
+)
+
+// Extension describes a linkerd extension which is rendered by its own CLI subcommand
+type Extension struct {
+	// Operation is the key of the operation managing the extension
+	Operation string
+	// Command is the linkerd CLI subcommand of the extension
+	Command     string
+	Description string
+	// Namespace is the namespace the extension is installed into
+	Namespace string
+
+	// MinStable and MinEdge are the first stable and edge releases
+	// shipping the extension in the "major.minor" format
+	MinStable string
+	MinEdge   string
+}
+
+// Extensions are the linkerd extensions supported by the adapter
+var Extensions = []Extension{
+	{
+		Operation:   LinkerdViz,
+		Command:     "viz",
+		Description: "Linkerd Viz Extension",
+		Namespace:   "linkerd-viz",
+		MinStable:   "2.10",
+		MinEdge:     "21.1",
+	},
+	{
+		Operation:   LinkerdJaeger,
+		Command:     "jaeger",
+		Description: "Linkerd Jaeger Extension",
+		Namespace:   "linkerd-jaeger",
+		MinStable:   "2.10",
+		MinEdge:     "21.1",
+	},
+	{
+		Operation:   LinkerdMulticluster,
+		Command:     "multicluster",
+		Description: "Linkerd Multicluster Extension",
+		Namespace:   "linkerd-multicluster",
+		MinStable:   "2.8",
+		MinEdge:     "20.4",
+	},
+}
+
+// GetExtension returns the extension managed by the operation
+func GetExtension(operation string) (Extension, bool) {
+	for _, ext := range Extensions {
+		if ext.Operation == operation {
+			return ext, true
+		}
+	}
+	return Extension{}, false
+}
+
+// Supports returns true if the linkerd release, e.g. stable-2.10.2 or
+// edge-21.3.1, ships the extension
+func (ext Extension) Supports(version string) bool {
+	channel, release := splitVersion(version)
+	switch channel {
+	case "stable":
+		return compareReleases(release, ext.MinStable) >= 0
+	case "edge":
+		return compareReleases(release, ext.MinEdge) >= 0
+	}
+	return false
+}
+
+// supportedVersions filters the versions down to those shipping the extension
+func (ext Extension) supportedVersions(versions []adapter.Version) []adapter.Version {
+	supported := []adapter.Version{}
+	for _, v := range versions {
+		if ext.Supports(string(v)) {
+			supported = append(supported, v)
+		}
+	}
+	return supported
+}
+
+// IsReleaseVersion returns true if the version names a stable or an edge
+// release, e.g. stable-2.10.2 or edge-21.3.1
+func IsReleaseVersion(version string) bool {
+	channel, release := splitVersion(version)
+	return (channel == "stable" || channel == "edge") && release != ""
+}
+
+// splitVersion splits a linkerd release name into its channel and its number
+func splitVersion(version string) (string, string) {
+	parts := strings.SplitN(version, "-", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// compareReleases compares the major and minor components of two release numbers
+func compareReleases(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < 2; i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/layer5io/meshery-adapter-library/adapter"
+)
+
+func TestExtensionSupports(t *testing.T) {
+	viz, _ := GetExtension(LinkerdViz)
+	multicluster, _ := GetExtension(LinkerdMulticluster)
+
+	tests := []struct {
+		ext       Extension
+		version   string
+		supported bool
+	}{
+		{ext: viz, version: "stable-2.10.0", supported: true},
+		{ext: viz, version: "stable-2.11.1", supported: true},
+		{ext: viz, version: "stable-2.9.4"},
+		{ext: viz, version: "edge-21.1.1", supported: true},
+		{ext: viz, version: "edge-20.12.4"},
+		{ext: multicluster, version: "stable-2.8.1", supported: true},
+		{ext: multicluster, version: "stable-2.7.1"},
+		{ext: viz, version: "latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ext.Operation+"/"+tt.version, func(t *testing.T) {
+			if supported := tt.ext.Supports(tt.version); supported != tt.supported {
+				t.Errorf("got %t, want %t", supported, tt.supported)
+			}
+		})
+	}
+}
+
+func TestSupportedVersions(t *testing.T) {
+	jaeger, ok := GetExtension(LinkerdJaeger)
+	if !ok {
+		t.Fatal("jaeger extension not found")
+	}
+
+	versions := []adapter.Version{"stable-2.9.4", "stable-2.10.2", "edge-21.3.1", "edge-20.11.1"}
+	want := []adapter.Version{"stable-2.10.2", "edge-21.3.1"}
+	if got := jaeger.supportedVersions(versions); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestIsReleaseVersion(t *testing.T) {
+	tests := map[string]bool{
+		"stable-2.10.2": true,
+		"edge-21.3.1":   true,
+		"stable-":       false,
+		"2.10.2":        false,
+		"nightly-2.10":  false,
+	}
+
+	for version, want := range tests {
+		if got := IsReleaseVersion(version); got != want {
+			t.Errorf("IsReleaseVersion(%s) = %t, want %t", version, got, want)
+		}
+	}
+}
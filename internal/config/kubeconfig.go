@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	KubeconfigSourceExplicit  = "explicit kubeconfig"
+	KubeconfigSourceEnv       = "$KUBECONFIG"
+	KubeconfigSourceInCluster = "in-cluster service account"
+)
+
+// userKubeconfig is the $KUBECONFIG the adapter was started with, the
+// adapter points the variable at the kubeconfig in use later on
+var userKubeconfig = os.Getenv("KUBECONFIG")
+
+// KubeconfigSource describes where the kubernetes credentials of the adapter come from
+type KubeconfigSource struct {
+	Name string
+	// Path of the kubeconfig, empty for the in-cluster service account
+	Path string
+}
+
+// DetectKubeconfigSource walks the credential chain: the kubeconfig managed by the
+// adapter, which meshery uploads, then $KUBECONFIG and finally the service account
+// of the pod if the adapter runs inside a cluster
+func DetectKubeconfigSource() (KubeconfigSource, error) {
+	if hasContexts(KubeConfigPath()) {
+		return KubeconfigSource{Name: KubeconfigSourceExplicit, Path: KubeConfigPath()}, nil
+	}
+
+	if userKubeconfig != "" && hasContexts(userKubeconfig) {
+		return KubeconfigSource{Name: KubeconfigSourceEnv, Path: userKubeconfig}, nil
+	}
+
+	if _, err := rest.InClusterConfig(); err == nil {
+		return KubeconfigSource{Name: KubeconfigSourceInCluster}, nil
+	}
+
+	return KubeconfigSource{}, ErrNoKubeconfig
+}
+
+// hasContexts returns true if the file is a kubeconfig with at least one context
+func hasContexts(path string) bool {
+	cfg, err := clientcmd.LoadFromFile(path)
+	return err == nil && len(cfg.Contexts) > 0
+}
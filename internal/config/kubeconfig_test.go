@@ -0,0 +1,78 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	configprovider "github.com/layer5io/meshery-adapter-library/config/provider"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kind
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: kind
+  context:
+    cluster: kind
+current-context: kind
+`
+
+func TestDetectKubeconfigSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	explicitDir := filepath.Join(dir, "explicit")
+	if err = os.Mkdir(explicitDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	env := filepath.Join(dir, "env.yaml")
+	if err = ioutil.WriteFile(env, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(path, user string) {
+		KubeConfig[configprovider.FilePath] = path
+		userKubeconfig = user
+	}(KubeConfig[configprovider.FilePath], userKubeconfig)
+	KubeConfig[configprovider.FilePath] = explicitDir
+	// The tests must not pick up the service account of a pod they run in
+	if host, ok := os.LookupEnv("KUBERNETES_SERVICE_HOST"); ok {
+		os.Unsetenv("KUBERNETES_SERVICE_HOST")
+		defer os.Setenv("KUBERNETES_SERVICE_HOST", host)
+	}
+
+	userKubeconfig = ""
+	if _, err = DetectKubeconfigSource(); err != ErrNoKubeconfig {
+		t.Errorf("got %v without credentials, want ErrNoKubeconfig", err)
+	}
+
+	userKubeconfig = env
+	source, err := DetectKubeconfigSource()
+	if err != nil || source.Name != KubeconfigSourceEnv || source.Path != env {
+		t.Errorf("got %+v, %v, want $KUBECONFIG", source, err)
+	}
+
+	// A kubeconfig without contexts, e.g. before meshery uploaded one, is skipped
+	if err = ioutil.WriteFile(KubeConfigPath(), []byte("apiVersion: v1\nkind: Config\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if source, err = DetectKubeconfigSource(); err != nil || source.Name != KubeconfigSourceEnv {
+		t.Errorf("got %+v, %v, want $KUBECONFIG", source, err)
+	}
+
+	if err = ioutil.WriteFile(KubeConfigPath(), []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	source, err = DetectKubeconfigSource()
+	if err != nil || source.Name != KubeconfigSourceExplicit || source.Path != KubeConfigPath() {
+		t.Errorf("got %+v, %v, want the explicit kubeconfig", source, err)
+	}
+}
@@ -20,10 +20,108 @@ func getOperations(dev adapter.Operations) adapter.Operations {
 		AdditionalProperties: map[string]string{},
 	}
 
+	dev[LinkerdCNI] = &adapter.Operation{
+		Type:                 int32(meshes.OpCategory_INSTALL),
+		Description:          "Linkerd CNI Plugin",
+		Versions:             versions,
+		Templates:            []adapter.Template{},
+		AdditionalProperties: map[string]string{},
+	}
+
+	for _, ext := range Extensions {
+		dev[ext.Operation] = &adapter.Operation{
+			Type:                 int32(meshes.OpCategory_INSTALL),
+			Description:          ext.Description,
+			Versions:             ext.supportedVersions(versions),
+			Templates:            []adapter.Template{},
+			AdditionalProperties: map[string]string{},
+		}
+	}
+
 	dev[AnnotateNamespace] = &adapter.Operation{
 		Type:        int32(meshes.OpCategory_CONFIGURE),
 		Description: "Annotate Namespace",
 	}
 
+	dev[InjectWorkloads] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Inject Linkerd Proxy into Workloads",
+	}
+
+	dev[RotateIssuer] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Rotate Identity Issuer Certificate",
+	}
+
+	dev[RotateTrustAnchor] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Rotate Trust Anchor",
+	}
+
+	dev[MulticlusterLink] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Link Clusters for Multicluster Communication",
+	}
+
+	dev[ExportServices] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Export Services to Linked Clusters",
+	}
+
+	dev[UpgradeProxies] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Upgrade Data Plane Proxies",
+	}
+
+	dev[ProxyConfig] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Configure Proxies",
+	}
+
+	dev[ServiceProfile] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Generate Service Profile from OpenAPI or Protobuf",
+	}
+
+	dev[RoutePolicy] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Configure Retries and Timeouts of Routes",
+	}
+
+	dev[Canary] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Progressive Canary Rollout with Traffic Split",
+	}
+
+	dev[FaultInjection] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Inject HTTP Faults with Traffic Split",
+	}
+
+	dev[AuthorizationPolicy] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Authorize Clients of a Workload Port",
+	}
+
+	dev[DefaultDenyPolicy] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_CONFIGURE),
+		Description: "Deny Unauthorized Inbound Traffic of Namespace",
+	}
+
+	dev[MeshStatus] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_VALIDATE),
+		Description: "Mesh Coverage Report",
+	}
+
+	dev[MTLSVerify] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_VALIDATE),
+		Description: "Verify Mutual TLS between Services",
+	}
+
+	dev[Stats] = &adapter.Operation{
+		Type:        int32(meshes.OpCategory_VALIDATE),
+		Description: "Golden Metrics of Meshed Deployments",
+	}
+
 	return dev
 }
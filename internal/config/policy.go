@@ -0,0 +1,46 @@
+package config
+
+const (
+	ServerAuthorizationAPI = "ServerAuthorization"
+	AuthorizationPolicyAPI = "AuthorizationPolicy"
+)
+
+// PolicyAPI describes the resources a linkerd release authorizes inbound traffic with
+type PolicyAPI struct {
+	// Name is the kind of the resource authorizing the clients of a server
+	Name string
+
+	// MinStable and MinEdge are the first stable and edge releases
+	// serving the resources in the "major.minor" format
+	MinStable string
+	MinEdge   string
+}
+
+// PolicyAPIs are the policy APIs supported by the adapter, from the newest to the oldest
+var PolicyAPIs = []PolicyAPI{
+	{Name: AuthorizationPolicyAPI, MinStable: "2.12", MinEdge: "22.5"},
+	{Name: ServerAuthorizationAPI, MinStable: "2.11", MinEdge: "21.9"},
+}
+
+// GetPolicyAPI returns the newest policy API of the linkerd release
+func GetPolicyAPI(version string) (PolicyAPI, bool) {
+	for _, api := range PolicyAPIs {
+		if api.Supports(version) {
+			return api, true
+		}
+	}
+	return PolicyAPI{}, false
+}
+
+// Supports returns true if the linkerd release, e.g. stable-2.11.1 or
+// edge-21.9.2, serves the policy API
+func (api PolicyAPI) Supports(version string) bool {
+	channel, release := splitVersion(version)
+	switch channel {
+	case "stable":
+		return compareReleases(release, api.MinStable) >= 0
+	case "edge":
+		return compareReleases(release, api.MinEdge) >= 0
+	}
+	return false
+}
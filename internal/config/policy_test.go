@@ -0,0 +1,27 @@
+package config
+
+import "testing"
+
+func TestGetPolicyAPI(t *testing.T) {
+	tests := []struct {
+		version string
+		api     string
+	}{
+		{version: "stable-2.13.4", api: AuthorizationPolicyAPI},
+		{version: "stable-2.12.0", api: AuthorizationPolicyAPI},
+		{version: "stable-2.11.1", api: ServerAuthorizationAPI},
+		{version: "edge-22.5.1", api: AuthorizationPolicyAPI},
+		{version: "edge-22.4.1", api: ServerAuthorizationAPI},
+		{version: "edge-21.8.4"},
+		{version: "stable-2.10.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			api, ok := GetPolicyAPI(tt.version)
+			if ok != (tt.api != "") || api.Name != tt.api {
+				t.Errorf("got %q, %t, want %q", api.Name, ok, tt.api)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ProxyConfigPrefix is the prefix of the annotations configuring the linkerd proxy
+const ProxyConfigPrefix = "config.linkerd.io/"
+
+// Kinds of values of the proxy settings
+const (
+	proxyQuantity = "quantity"
+	proxyLogLevel = "log-level"
+	proxyPorts    = "ports"
+	proxyBool     = "bool"
+)
+
+var proxyLogLevels = map[string]bool{"trace": true, "debug": true, "info": true, "warn": true, "error": true, "off": true}
+
+// ProxySetting describes a proxy configuration annotation
+type ProxySetting struct {
+	// Name of the setting, the annotation is config.linkerd.io/<Name>
+	Name string
+	Kind string
+
+	// MinStable and MinEdge are the first stable and edge releases
+	// supporting the annotation in the "major.minor" format
+	MinStable string
+	MinEdge   string
+}
+
+// ProxySettings are the proxy settings managed by the proxy-config operation
+var ProxySettings = []ProxySetting{
+	{Name: "proxy-cpu-request", Kind: proxyQuantity, MinStable: "2.3", MinEdge: "19.3"},
+	{Name: "proxy-cpu-limit", Kind: proxyQuantity, MinStable: "2.3", MinEdge: "19.3"},
+	{Name: "proxy-memory-request", Kind: proxyQuantity, MinStable: "2.3", MinEdge: "19.3"},
+	{Name: "proxy-memory-limit", Kind: proxyQuantity, MinStable: "2.3", MinEdge: "19.3"},
+	{Name: "proxy-log-level", Kind: proxyLogLevel, MinStable: "2.3", MinEdge: "19.3"},
+	{Name: "skip-inbound-ports", Kind: proxyPorts, MinStable: "2.3", MinEdge: "19.3"},
+	{Name: "skip-outbound-ports", Kind: proxyPorts, MinStable: "2.3", MinEdge: "19.3"},
+	{Name: "enable-debug-sidecar", Kind: proxyBool, MinStable: "2.6", MinEdge: "19.9"},
+	{Name: "opaque-ports", Kind: proxyPorts, MinStable: "2.10", MinEdge: "20.12"},
+}
+
+// GetProxySetting returns the proxy setting with the given name
+func GetProxySetting(name string) (ProxySetting, bool) {
+	for _, s := range ProxySettings {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return ProxySetting{}, false
+}
+
+// Annotation returns the annotation of the setting
+func (s ProxySetting) Annotation() string {
+	return ProxyConfigPrefix + s.Name
+}
+
+// Supports returns true if the linkerd release, e.g. stable-2.10.2 or
+// edge-21.3.1, supports the setting
+func (s ProxySetting) Supports(version string) bool {
+	channel, release := splitVersion(version)
+	switch channel {
+	case "stable":
+		return compareReleases(release, s.MinStable) >= 0
+	case "edge":
+		return compareReleases(release, s.MinEdge) >= 0
+	}
+	return false
+}
+
+// Validate checks the value of the setting
+func (s ProxySetting) Validate(value string) error {
+	switch s.Kind {
+	case proxyQuantity:
+		if _, err := resource.ParseQuantity(value); err != nil {
+			return fmt.Errorf("%s: %s", s.Name, err)
+		}
+	case proxyBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s: %q is not a boolean", s.Name, value)
+		}
+	case proxyLogLevel:
+		// The log level is a filter such as "warn,linkerd=info"
+		for _, directive := range strings.Split(value, ",") {
+			level := directive
+			if i := strings.Index(directive, "="); i >= 0 {
+				level = directive[i+1:]
+			}
+			if !proxyLogLevels[strings.TrimSpace(level)] {
+				return fmt.Errorf("%s: invalid level in %q", s.Name, directive)
+			}
+		}
+	case proxyPorts:
+		for _, p := range strings.Split(value, ",") {
+			bounds := strings.SplitN(strings.TrimSpace(p), "-", 2)
+			ports := make([]int, 0, len(bounds))
+			for _, b := range bounds {
+				port, err := strconv.Atoi(b)
+				if err != nil || port < 1 || port > 65535 {
+					return fmt.Errorf("%s: invalid port %q", s.Name, p)
+				}
+				ports = append(ports, port)
+			}
+			if len(ports) == 2 && ports[0] > ports[1] {
+				return fmt.Errorf("%s: invalid port range %q, the low end is above the high end", s.Name, p)
+			}
+		}
+	}
+	return nil
+}
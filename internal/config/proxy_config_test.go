@@ -0,0 +1,66 @@
+package config
+
+import "testing"
+
+func TestProxySettingValidate(t *testing.T) {
+	tests := []struct {
+		setting string
+		value   string
+		valid   bool
+	}{
+		{setting: "proxy-cpu-limit", value: "500m", valid: true},
+		{setting: "proxy-memory-request", value: "64Mi", valid: true},
+		{setting: "proxy-memory-limit", value: "a lot", valid: false},
+		{setting: "proxy-log-level", value: "warn", valid: true},
+		{setting: "proxy-log-level", value: "warn,linkerd=info", valid: true},
+		{setting: "proxy-log-level", value: "warn,linkerd=verbose", valid: false},
+		{setting: "skip-inbound-ports", value: "25,587", valid: true},
+		{setting: "skip-outbound-ports", value: "4567-4570, 3306", valid: true},
+		{setting: "opaque-ports", value: "0", valid: false},
+		{setting: "opaque-ports", value: "8080-70000", valid: false},
+		{setting: "opaque-ports", value: "5000-4000", valid: false},
+		{setting: "opaque-ports", value: "4000-4000", valid: true},
+		{setting: "opaque-ports", value: "mysql", valid: false},
+		{setting: "enable-debug-sidecar", value: "true", valid: true},
+		{setting: "enable-debug-sidecar", value: "yes", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.setting+"="+tt.value, func(t *testing.T) {
+			s, ok := GetProxySetting(tt.setting)
+			if !ok {
+				t.Fatalf("unknown setting %s", tt.setting)
+			}
+			err := s.Validate(tt.value)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestProxySettingSupports(t *testing.T) {
+	s, _ := GetProxySetting("opaque-ports")
+
+	tests := []struct {
+		version   string
+		supported bool
+	}{
+		{version: "stable-2.10.0", supported: true},
+		{version: "stable-2.9.4", supported: false},
+		{version: "edge-20.12.1", supported: true},
+		{version: "edge-20.11.5", supported: false},
+		{version: "latest", supported: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if supported := s.Supports(tt.version); supported != tt.supported {
+				t.Errorf("got %t, want %t", supported, tt.supported)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+var (
+	readWrite = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+
+	// BindableRoles are the roles shipped by Linkerd and its extensions, the adapter
+	// may only bind these
+	BindableRoles = []string{
+		"linkerd-linkerd-identity",
+		"linkerd-linkerd-destination",
+		"linkerd-linkerd-proxy-injector",
+		"linkerd-linkerd-sp-validator",
+		"linkerd-heartbeat",
+		"linkerd-policy",
+		"linkerd-cni",
+		"linkerd-linkerd-viz-metrics-api",
+		"linkerd-linkerd-viz-prometheus",
+		"linkerd-linkerd-viz-tap",
+		"linkerd-linkerd-viz-tap-admin",
+		"linkerd-linkerd-viz-web-check",
+		"linkerd-linkerd-viz-web-admin",
+		"linkerd-linkerd-viz-web-api",
+		"linkerd-jaeger-injector",
+		"linkerd-service-mirror-access-local-resources",
+		"linkerd-service-mirror-read-remote-creds",
+		"linkerd-service-mirror-remote-access-default",
+		"system:auth-delegator",
+		"extension-apiserver-authentication-reader",
+	}
+
+	// RequiredPermissions are the permissions the adapter needs to manage Linkerd,
+	// they have to be kept in sync with the roles in install/kubernetes/rbac.yaml
+	RequiredPermissions = []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"namespaces", "pods", "services", "endpoints", "configmaps", "serviceaccounts"},
+			Verbs:     readWrite,
+		},
+		// Secrets are granted by a Role in each control plane namespace only
+		{
+			APIGroups: []string{""},
+			Resources: []string{"secrets"},
+			Verbs:     readWrite,
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"pods/portforward"},
+			Verbs:     []string{"create"},
+		},
+		{
+			APIGroups: []string{"apps"},
+			Resources: []string{"deployments", "daemonsets", "statefulsets", "replicasets"},
+			Verbs:     readWrite,
+		},
+		{
+			APIGroups: []string{"batch"},
+			Resources: []string{"jobs", "cronjobs"},
+			Verbs:     readWrite,
+		},
+		{
+			APIGroups: []string{"rbac.authorization.k8s.io"},
+			Resources: []string{"clusterroles", "clusterrolebindings", "roles", "rolebindings"},
+			Verbs:     readWrite,
+		},
+		{
+			APIGroups:     []string{"rbac.authorization.k8s.io"},
+			Resources:     []string{"clusterroles", "roles"},
+			Verbs:         []string{"bind"},
+			ResourceNames: BindableRoles,
+		},
+		{
+			APIGroups: []string{"apiextensions.k8s.io"},
+			Resources: []string{"customresourcedefinitions"},
+			Verbs:     readWrite,
+		},
+		{
+			APIGroups: []string{"admissionregistration.k8s.io"},
+			Resources: []string{"mutatingwebhookconfigurations", "validatingwebhookconfigurations"},
+			Verbs:     readWrite,
+		},
+		{
+			APIGroups: []string{"apiregistration.k8s.io"},
+			Resources: []string{"apiservices"},
+			Verbs:     readWrite,
+		},
+		{
+			APIGroups: []string{"policy"},
+			Resources: []string{"podsecuritypolicies", "poddisruptionbudgets"},
+			Verbs:     append([]string{"use"}, readWrite...),
+		},
+		{
+			APIGroups: []string{"linkerd.io", "policy.linkerd.io", "multicluster.linkerd.io", "split.smi-spec.io", "cert-manager.io"},
+			Resources: []string{"*"},
+			Verbs:     readWrite,
+		},
+	}
+)
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// TestRequiredPermissions makes sure the published roles grant the permissions the adapter verifies
+func TestRequiredPermissions(t *testing.T) {
+	data, err := ioutil.ReadFile("../../install/kubernetes/rbac.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var clusterRules []rbacv1.PolicyRule
+	namespaceRules := map[string][]rbacv1.PolicyRule{}
+	for _, doc := range strings.Split(string(data), "\n---\n") {
+		role := rbacv1.Role{}
+		if err = yaml.Unmarshal([]byte(doc), &role); err != nil {
+			t.Fatal(err)
+		}
+		switch role.Kind {
+		case "ClusterRole":
+			clusterRules = append(clusterRules, role.Rules...)
+		case "Role":
+			namespaceRules[role.Namespace] = append(namespaceRules[role.Namespace], role.Rules...)
+		}
+	}
+
+	for _, ns := range []string{"linkerd", LinkerdViz, LinkerdJaeger, LinkerdMulticluster} {
+		rules := append(append([]rbacv1.PolicyRule{}, clusterRules...), namespaceRules[ns]...)
+		for _, required := range RequiredPermissions {
+			for _, group := range required.APIGroups {
+				for _, resource := range required.Resources {
+					for _, verb := range required.Verbs {
+						names := required.ResourceNames
+						if len(names) == 0 {
+							names = []string{""}
+						}
+						for _, name := range names {
+							if !grants(rules, group, resource, verb, name) {
+								t.Errorf("%s: %s %s/%s %s is not granted", ns, verb, group, resource, name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, rule := range clusterRules {
+		if contains(rule.Resources, "secrets") {
+			t.Error("the cluster role grants secrets in every namespace")
+		}
+		if contains(rule.Verbs, "escalate") || contains(rule.Verbs, "*") {
+			t.Errorf("the cluster role grants %v on %v", rule.Verbs, rule.Resources)
+		}
+		if contains(rule.Verbs, "bind") && len(rule.ResourceNames) == 0 {
+			t.Errorf("the cluster role grants bind on every role")
+		}
+	}
+}
+
+func grants(rules []rbacv1.PolicyRule, group, resource, verb, name string) bool {
+	for _, rule := range rules {
+		if len(rule.ResourceNames) > 0 && !contains(rule.ResourceNames, name) {
+			continue
+		}
+		if contains(rule.APIGroups, group) && contains(rule.Resources, resource) && contains(rule.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
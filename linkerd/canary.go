@@ -0,0 +1,173 @@
+package linkerd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+)
+
+const (
+	defaultCanaryInterval    = time.Minute
+	defaultCanarySuccessRate = 0.99
+	defaultCanaryLatencyP99  = 500 * time.Millisecond
+	trafficSplitSuffix       = "-canary"
+	canarySuccessRateQuery   = `sum(rate(response_total{namespace="%[1]s", deployment="%[2]s", direction="inbound", classification!="failure"}[%[3]s])) / sum(rate(response_total{namespace="%[1]s", deployment="%[2]s", direction="inbound"}[%[3]s]))`
+	canaryLatencyP99Query    = `histogram_quantile(0.99, sum(rate(response_latency_ms_bucket{namespace="%[1]s", deployment="%[2]s", direction="inbound"}[%[3]s])) by (le))`
+	trafficSplitTemplate     = `apiVersion: split.smi-spec.io/v1alpha1
+kind: TrafficSplit
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  service: %[3]s
+  backends:
+  - service: %[4]s
+    weight: %[6]d
+  - service: %[5]s
+    weight: %[7]d
+`
+)
+
+var defaultCanarySteps = []int{10, 25, 50, 100}
+
+// canaryWait waits for the traffic of a step to be observed, tests replace it
+var canaryWait = time.Sleep
+
+// CanaryOptions is the body of the canary operation
+type CanaryOptions struct {
+	// Service is the apex service the clients talk to, its traffic is split between
+	// the Primary and the Canary service
+	Service string `json:"service"`
+	Primary string `json:"primary"`
+	Canary  string `json:"canary"`
+	// CanaryDeployment is the deployment behind the canary service whose metrics are
+	// checked, it defaults to the name of the canary service
+	CanaryDeployment string `json:"canaryDeployment,omitempty"`
+
+	// Steps are the percentages of traffic sent to the canary, defaults to 10, 25, 50 and 100
+	Steps []int `json:"steps,omitempty"`
+	// Interval between two steps the metrics are checked over, defaults to one minute,
+	// it has to be at least 20s for Prometheus to have two samples of the traffic
+	Interval string `json:"interval,omitempty"`
+
+	// MinSuccessRate is the lowest acceptable success rate of the canary, defaults to 0.99
+	MinSuccessRate *float64 `json:"minSuccessRate,omitempty"`
+	// MaxLatencyP99 is the highest acceptable p99 latency of the canary, defaults to 500ms
+	MaxLatencyP99 string `json:"maxLatencyP99,omitempty"`
+
+	PrometheusOptions `json:",inline"`
+}
+
+// runCanary shifts the traffic of the apex service from the primary to the canary step by
+// step with a TrafficSplit. After every step the success rate and the latency of the canary
+// are checked, all traffic is shifted back to the primary when a threshold is breached.
+// A delete operation removes the TrafficSplit
+func (linkerd *Linkerd) runCanary(opID, namespace, body string, del bool) (string, error) {
+	st := status.Deploying
+	opts := CanaryOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return st, err
+	}
+	if opts.Service == "" || opts.Primary == "" || opts.Canary == "" {
+		return st, ErrCanary(fmt.Errorf("service, primary and canary have to be given"))
+	}
+
+	if del {
+		if err := linkerd.applyTrafficSplit(namespace, opts, 0, true); err != nil {
+			return status.Removing, err
+		}
+		return status.Removed, nil
+	}
+
+	if opts.CanaryDeployment == "" {
+		opts.CanaryDeployment = opts.Canary
+	}
+	if len(opts.Steps) == 0 {
+		opts.Steps = defaultCanarySteps
+	}
+	for i, step := range opts.Steps {
+		if step < 0 || step > 100 || (i > 0 && step <= opts.Steps[i-1]) {
+			return st, ErrCanary(fmt.Errorf("steps have to be increasing percentages"))
+		}
+	}
+	interval, err := parseQueryWindow(opts.Interval, defaultCanaryInterval)
+	if err != nil {
+		return st, err
+	}
+	maxLatency := defaultCanaryLatencyP99
+	if opts.MaxLatencyP99 != "" {
+		d, err := time.ParseDuration(opts.MaxLatencyP99)
+		if err != nil {
+			return st, ErrParseOperationBody(err)
+		}
+		maxLatency = d
+	}
+	minSuccessRate := defaultCanarySuccessRate
+	if opts.MinSuccessRate != nil {
+		minSuccessRate = *opts.MinSuccessRate
+	}
+
+	prom, err := linkerd.prometheus(opts.PrometheusOptions)
+	if err != nil {
+		return st, err
+	}
+	defer prom.Close()
+
+	window := fmt.Sprintf("%ds", int(interval.Seconds()))
+	for _, weight := range opts.Steps {
+		if err = linkerd.applyTrafficSplit(namespace, opts, weight, false); err != nil {
+			return st, err
+		}
+		linkerd.streamProgress(opID, fmt.Sprintf("Canary %s receives %d%% of the traffic", opts.Canary, weight), fmt.Sprintf("Checking the metrics of deployment %s in %s", opts.CanaryDeployment, interval))
+		canaryWait(interval)
+
+		successRate, latency, err := linkerd.canaryMetrics(prom, namespace, opts.CanaryDeployment, window)
+		if err == nil && successRate < minSuccessRate {
+			err = fmt.Errorf("success rate %.4f is below %.4f", successRate, minSuccessRate)
+		}
+		if err == nil && latency > maxLatency {
+			err = fmt.Errorf("p99 latency %s is above %s", latency, maxLatency)
+		}
+		if err != nil {
+			if rerr := linkerd.applyTrafficSplit(namespace, opts, 0, false); rerr != nil {
+				return st, rerr
+			}
+			return st, ErrCanary(fmt.Errorf("rolled back to %s at %d%%: %s", opts.Primary, weight, err))
+		}
+
+		linkerd.streamProgress(opID, fmt.Sprintf("Canary step %d%% passed", weight), fmt.Sprintf("Success rate %.4f, p99 latency %s", successRate, latency))
+	}
+
+	return status.Deployed, nil
+}
+
+// applyTrafficSplit sends the given percentage of the traffic of the apex service to the canary
+func (linkerd *Linkerd) applyTrafficSplit(namespace string, opts CanaryOptions, weight int, del bool) error {
+	manifest := fmt.Sprintf(trafficSplitTemplate, opts.Service+trafficSplitSuffix, namespace, opts.Service, opts.Primary, opts.Canary, 100-weight, weight)
+	if err := linkerd.applyManifest([]byte(manifest), del, namespace); err != nil {
+		return ErrCanary(err)
+	}
+	return nil
+}
+
+// canaryMetrics returns the success rate and the p99 latency of the inbound traffic of the deployment
+func (linkerd *Linkerd) canaryMetrics(prom *prometheusClient, namespace, deployment, window string) (float64, time.Duration, error) {
+	successRate, ok, err := prom.scalar(fmt.Sprintf(canarySuccessRateQuery, namespace, deployment, window))
+	if err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		return 0, 0, fmt.Errorf("no requests to deployment %s observed", deployment)
+	}
+
+	latency, ok, err := prom.scalar(fmt.Sprintf(canaryLatencyP99Query, namespace, deployment, window))
+	if err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		return 0, 0, fmt.Errorf("no latency of deployment %s observed", deployment)
+	}
+
+	return successRate, time.Duration(latency * float64(time.Millisecond)), nil
+}
@@ -0,0 +1,102 @@
+package linkerd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+	"github.com/layer5io/meshkit/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRunCanary(t *testing.T) {
+	waits := 0
+	canaryWait = func(time.Duration) { waits++ }
+	t.Cleanup(func() { canaryWait = time.Sleep })
+
+	tests := []struct {
+		name string
+		// values of the queries of the canary
+		successRate string
+		latencyP99  string
+		// steps passed before the rollback, all of them if the canary is promoted
+		steps  int
+		weight int64
+	}{
+		{name: "promoted", successRate: "1", latencyP99: "30", steps: 3, weight: 100},
+		{name: "failing requests", successRate: "0.8", latencyP99: "30", steps: 1},
+		{name: "slow responses", successRate: "1", latencyP99: "900", steps: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits = 0
+			linkerd, cluster := newFakeLinkerd(t)
+			prom := newFakePrometheus(t, []map[string]string{{}})
+			prom.set(`classification!="failure"`, tt.successRate)
+			prom.set("histogram_quantile(0.99,", tt.latencyP99)
+
+			body, _ := json.Marshal(CanaryOptions{
+				Service:           "books",
+				Primary:           "books-v1",
+				Canary:            "books-v2",
+				Steps:             []int{25, 50, 100},
+				PrometheusOptions: PrometheusOptions{PrometheusURL: prom.URL},
+			})
+			st, err := linkerd.runCanary("", "bookapp", string(body), false)
+			if tt.weight == 100 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if st != status.Deployed {
+					t.Errorf("got status %s, want %s", st, status.Deployed)
+				}
+			} else if errors.GetCode(err) != ErrCanaryCode {
+				t.Errorf("got %v, want ErrCanary", err)
+			}
+			if waits != tt.steps {
+				t.Errorf("checked %d steps, want %d", waits, tt.steps)
+			}
+
+			split := cluster.object("split.smi-spec.io/v1alpha1", "trafficsplits", "bookapp", "books"+trafficSplitSuffix)
+			if split == nil {
+				t.Fatal("traffic split not found")
+			}
+			backends, _, _ := unstructured.NestedSlice(split, "spec", "backends")
+			weights := map[string]int64{}
+			for _, b := range backends {
+				backend := b.(map[string]interface{})
+				weight, _ := backend["weight"].(float64)
+				weights[backend["service"].(string)] = int64(weight)
+			}
+			if weights["books-v2"] != tt.weight || weights["books-v1"] != 100-tt.weight {
+				t.Errorf("got weights %v, want %d%% for the canary", weights, tt.weight)
+			}
+		})
+	}
+}
+
+func TestRunCanaryOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "missing canary", body: `{"service": "books", "primary": "books-v1"}`},
+		{name: "decreasing steps", body: `{"service": "books", "primary": "books-v1", "canary": "books-v2", "steps": [50, 25]}`},
+		{name: "step above 100", body: `{"service": "books", "primary": "books-v1", "canary": "books-v2", "steps": [50, 150]}`},
+		{name: "interval below the scrape interval", body: `{"service": "books", "primary": "books-v1", "canary": "books-v2", "interval": "10s"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkerd, cluster := newFakeLinkerd(t)
+			if _, err := linkerd.runCanary("", "bookapp", tt.body, false); err == nil {
+				t.Error("expected an error")
+			}
+			if m := cluster.mutations(); len(m) > 0 {
+				t.Errorf("got requests %v", m)
+			}
+		})
+	}
+}
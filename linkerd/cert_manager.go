@@ -0,0 +1,275 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	certManagerGroupVersion = "cert-manager.io/v1"
+
+	defaultTrustAnchorSecret = "linkerd-trust-anchor"
+	defaultIssuerDuration    = "48h"
+	defaultIssuerRenewBefore = "25h"
+
+	issuerSecretTimeout = 2 * time.Minute
+
+	// externalIssuerFlag makes the control plane use the issuer secret managed by cert-manager
+	externalIssuerFlag = "--identity-external-issuer"
+	// issuerCAKey is the key of the trust anchor in the issuer secret, the identity
+	// component requires it next to the certificate and the key in external issuer mode
+	issuerCAKey = "ca.crt"
+
+	// managedByLabel marks the trust anchor secret and the namespace the adapter
+	// created for cert-manager, they are removed again on uninstall
+	managedByLabel   = "app.kubernetes.io/managed-by"
+	managedByMeshery = "meshery"
+)
+
+var (
+	issuerResource      = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "issuers"}
+	certificateResource = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+)
+
+// certManagerTemplate is the cert-manager Issuer backed by the trust anchor
+// and the Certificate which keeps the identity issuer secret up to date
+const certManagerTemplate = `apiVersion: cert-manager.io/v1
+kind: Issuer
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+spec:
+  ca:
+    secretName: %[1]s
+---
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: %[3]s
+  namespace: %[2]s
+spec:
+  secretName: %[3]s
+  duration: %[4]s
+  renewBefore: %[5]s
+  issuerRef:
+    name: %[1]s
+    kind: Issuer
+  commonName: identity.%[2]s.%[6]s
+  dnsNames:
+  - identity.%[2]s.%[6]s
+  isCA: true
+  privateKey:
+    algorithm: ECDSA
+  usages:
+  - cert sign
+  - crl sign
+  - server auth
+  - client auth
+`
+
+// CertManagerOptions configures cert-manager to manage the identity issuer
+type CertManagerOptions struct {
+	// TrustAnchorSecret is the kubernetes.io/tls secret in the control plane
+	// namespace holding the trust anchor, defaults to linkerd-trust-anchor
+	TrustAnchorSecret string `json:"trustAnchorSecret,omitempty"`
+
+	// TrustAnchorCert and TrustAnchorKey are the PEM encoded trust anchor,
+	// if set the trust anchor secret is created from them
+	TrustAnchorCert string `json:"trustAnchorCert,omitempty"`
+	TrustAnchorKey  string `json:"trustAnchorKey,omitempty"`
+
+	// TrustDomain of the mesh, defaults to cluster.local
+	TrustDomain string `json:"trustDomain,omitempty"`
+
+	// IssuerDuration and IssuerRenewBefore control the lifetime of the
+	// issuer certificate, they default to 48h and 25h respectively
+	IssuerDuration    string `json:"issuerDuration,omitempty"`
+	IssuerRenewBefore string `json:"issuerRenewBefore,omitempty"`
+}
+
+// setupCertManagerIssuer creates the cert-manager resources managing the identity
+// issuer, waits for cert-manager to populate the issuer secret and returns the
+// PEM encoded trust anchor the control plane has to be installed with
+func (linkerd *Linkerd) setupCertManagerIssuer(namespace string, opts *CertManagerOptions) ([]byte, error) {
+	if opts.TrustAnchorSecret == "" {
+		opts.TrustAnchorSecret = defaultTrustAnchorSecret
+	}
+	if opts.TrustDomain == "" {
+		opts.TrustDomain = defaultTrustDomain
+	}
+	if opts.IssuerDuration == "" {
+		opts.IssuerDuration = defaultIssuerDuration
+	}
+	if opts.IssuerRenewBefore == "" {
+		opts.IssuerRenewBefore = defaultIssuerRenewBefore
+	}
+
+	if err := linkerd.checkCertManager(); err != nil {
+		return nil, err
+	}
+
+	if opts.TrustAnchorCert != "" {
+		if err := linkerd.createTrustAnchorSecret(namespace, opts); err != nil {
+			return nil, ErrCertManager(err)
+		}
+	}
+	anchorSecret, err := linkerd.KubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), opts.TrustAnchorSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, ErrCertManager(err)
+	}
+	anchors, err := parseCertificates(anchorSecret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, ErrCertManager(err)
+	}
+
+	manifest := fmt.Sprintf(certManagerTemplate, opts.TrustAnchorSecret, namespace, identityIssuerSecret, opts.IssuerDuration, opts.IssuerRenewBefore, opts.TrustDomain)
+	if err = linkerd.applyManifest([]byte(manifest), false, namespace); err != nil {
+		return nil, ErrCertManager(err)
+	}
+
+	// cert-manager issues the certificate asynchronously
+	err = wait.PollImmediate(rolloutPollInterval, issuerSecretTimeout, func() (bool, error) {
+		secret, err := linkerd.KubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), identityIssuerSecret, metav1.GetOptions{})
+		if kubeerror.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return len(secret.Data[corev1.TLSCertKey]) > 0 && len(secret.Data[corev1.TLSPrivateKeyKey]) > 0 && len(secret.Data[issuerCAKey]) > 0, nil
+	})
+	if err != nil {
+		return nil, ErrCertManager(fmt.Errorf("secret %s/%s was not populated by cert-manager: %s", namespace, identityIssuerSecret, err))
+	}
+
+	return encodeCertificates(anchors), nil
+}
+
+// removeCertManagerIssuer deletes the cert-manager Certificate of the identity issuer
+// and the Issuer backed by the trust anchor, if cert-manager is installed at all
+func (linkerd *Linkerd) removeCertManagerIssuer(namespace string, opts *CertManagerOptions) error {
+	if opts == nil {
+		opts = &CertManagerOptions{}
+	}
+	if opts.TrustAnchorSecret == "" {
+		opts.TrustAnchorSecret = defaultTrustAnchorSecret
+	}
+
+	err := linkerd.checkCertManager()
+	if err == ErrCertManagerNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The certificate goes first, cert-manager would reissue the secret otherwise
+	err = linkerd.DynamicKubeClient.Resource(certificateResource).Namespace(namespace).Delete(context.TODO(), identityIssuerSecret, metav1.DeleteOptions{})
+	if err != nil && !kubeerror.IsNotFound(err) {
+		return ErrCertManager(err)
+	}
+	err = linkerd.DynamicKubeClient.Resource(issuerResource).Namespace(namespace).Delete(context.TODO(), opts.TrustAnchorSecret, metav1.DeleteOptions{})
+	if err != nil && !kubeerror.IsNotFound(err) {
+		return ErrCertManager(err)
+	}
+	return nil
+}
+
+// removeTrustAnchorSecret deletes the trust anchor secret and its namespace if the
+// adapter created them for cert-manager, both may be gone or have never existed
+func (linkerd *Linkerd) removeTrustAnchorSecret(namespace string, opts *CertManagerOptions) error {
+	name := defaultTrustAnchorSecret
+	if opts != nil && opts.TrustAnchorSecret != "" {
+		name = opts.TrustAnchorSecret
+	}
+
+	secrets := linkerd.KubeClient.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(context.TODO(), name, metav1.GetOptions{})
+	if err == nil && secret.Labels[managedByLabel] == managedByMeshery {
+		err = secrets.Delete(context.TODO(), name, metav1.DeleteOptions{})
+	}
+	if err != nil && !kubeerror.IsNotFound(err) {
+		return ErrCertManager(err)
+	}
+
+	namespaces := linkerd.KubeClient.CoreV1().Namespaces()
+	ns, err := namespaces.Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err == nil && ns.Labels[managedByLabel] == managedByMeshery {
+		err = namespaces.Delete(context.TODO(), namespace, metav1.DeleteOptions{})
+	}
+	if err != nil && !kubeerror.IsNotFound(err) {
+		return ErrCertManager(err)
+	}
+	return nil
+}
+
+// checkCertManager makes sure the cert-manager CRDs are installed in the cluster
+func (linkerd *Linkerd) checkCertManager() error {
+	resources, err := linkerd.KubeClient.Discovery().ServerResourcesForGroupVersion(certManagerGroupVersion)
+	if kubeerror.IsNotFound(err) {
+		return ErrCertManagerNotFound
+	}
+	if err != nil {
+		return ErrCertManager(err)
+	}
+
+	found := map[string]bool{}
+	for _, r := range resources.APIResources {
+		found[r.Kind] = true
+	}
+	if !found["Issuer"] || !found["Certificate"] {
+		return ErrCertManagerNotFound
+	}
+
+	return nil
+}
+
+// createTrustAnchorSecret creates or updates the trust anchor secret cert-manager signs the issuer with
+func (linkerd *Linkerd) createTrustAnchorSecret(namespace string, opts *CertManagerOptions) error {
+	anchors, err := parseCertificates([]byte(opts.TrustAnchorCert))
+	if err != nil {
+		return err
+	}
+	if _, _, err = matchTrustAnchor(anchors[:1], []byte(opts.TrustAnchorCert), []byte(opts.TrustAnchorKey)); err != nil {
+		return err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      opts.TrustAnchorSecret,
+			Namespace: namespace,
+			Labels:    map[string]string{managedByLabel: managedByMeshery},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte(opts.TrustAnchorCert),
+			corev1.TLSPrivateKeyKey: []byte(opts.TrustAnchorKey),
+		},
+	}
+
+	if _, err = linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{}); kubeerror.IsNotFound(err) {
+		_, err = linkerd.KubeClient.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   namespace,
+				Labels: map[string]string{managedByLabel: managedByMeshery},
+			},
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	client := linkerd.KubeClient.CoreV1().Secrets(namespace)
+	_, err = client.Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if kubeerror.IsNotFound(err) {
+		_, err = client.Create(context.TODO(), secret, metav1.CreateOptions{})
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package linkerd
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCheckCertManager(t *testing.T) {
+	linkerd, cluster := newFakeLinkerd(t)
+	if err := linkerd.checkCertManager(); err != nil {
+		t.Errorf("cert-manager not found: %s", err)
+	}
+
+	cluster.disable(certManagerGroupVersion)
+	if err := linkerd.checkCertManager(); err != ErrCertManagerNotFound {
+		t.Errorf("got %v, want ErrCertManagerNotFound", err)
+	}
+}
+
+func TestRemoveTrustAnchorSecret(t *testing.T) {
+	_, certPEM, keyPEM := newTestTrustAnchor(t, "root.linkerd.cluster.local")
+	opts := &CertManagerOptions{TrustAnchorSecret: defaultTrustAnchorSecret, TrustAnchorCert: string(certPEM), TrustAnchorKey: string(keyPEM)}
+
+	t.Run("created by the adapter", func(t *testing.T) {
+		linkerd, _ := newFakeLinkerd(t)
+		if err := linkerd.createTrustAnchorSecret("linkerd", opts); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := linkerd.removeTrustAnchorSecret("linkerd", opts); err != nil {
+			t.Fatal(err)
+		}
+		_, err := linkerd.KubeClient.CoreV1().Secrets("linkerd").Get(context.TODO(), defaultTrustAnchorSecret, metav1.GetOptions{})
+		if !kubeerror.IsNotFound(err) {
+			t.Errorf("trust anchor secret was not removed: %v", err)
+		}
+		_, err = linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), "linkerd", metav1.GetOptions{})
+		if !kubeerror.IsNotFound(err) {
+			t.Errorf("namespace was not removed: %v", err)
+		}
+
+		if err := linkerd.removeTrustAnchorSecret("linkerd", opts); err != nil {
+			t.Errorf("removing again: %s", err)
+		}
+	})
+
+	t.Run("created by the user", func(t *testing.T) {
+		linkerd, _ := newFakeLinkerd(t)
+		_, err := linkerd.KubeClient.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "linkerd"}}, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: defaultTrustAnchorSecret}}
+		if _, err = linkerd.KubeClient.CoreV1().Secrets("linkerd").Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = linkerd.removeTrustAnchorSecret("linkerd", nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = linkerd.KubeClient.CoreV1().Secrets("linkerd").Get(context.TODO(), defaultTrustAnchorSecret, metav1.GetOptions{}); err != nil {
+			t.Errorf("trust anchor secret was removed: %v", err)
+		}
+		if _, err = linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), "linkerd", metav1.GetOptions{}); err != nil {
+			t.Errorf("namespace was removed: %v", err)
+		}
+	})
+}
+
+func TestRemoveCertManagerIssuer(t *testing.T) {
+	t.Run("installed", func(t *testing.T) {
+		linkerd, cluster := newFakeLinkerd(t)
+		for _, r := range []struct {
+			kind, name string
+		}{{"Certificate", identityIssuerSecret}, {"Issuer", defaultTrustAnchorSecret}} {
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion(certManagerGroupVersion)
+			obj.SetKind(r.kind)
+			obj.SetName(r.name)
+			resource := certificateResource
+			if r.kind == "Issuer" {
+				resource = issuerResource
+			}
+			if _, err := linkerd.DynamicKubeClient.Resource(resource).Namespace("linkerd").Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := linkerd.removeCertManagerIssuer("linkerd", nil); err != nil {
+			t.Fatal(err)
+		}
+		if cluster.object(certManagerGroupVersion, "certificates", "linkerd", identityIssuerSecret) != nil {
+			t.Error("certificate was not removed")
+		}
+		if cluster.object(certManagerGroupVersion, "issuers", "linkerd", defaultTrustAnchorSecret) != nil {
+			t.Error("issuer was not removed")
+		}
+
+		if err := linkerd.removeCertManagerIssuer("linkerd", nil); err != nil {
+			t.Errorf("removing again: %s", err)
+		}
+	})
+
+	t.Run("not installed", func(t *testing.T) {
+		linkerd, cluster := newFakeLinkerd(t)
+		cluster.disable(certManagerGroupVersion)
+		if err := linkerd.removeCertManagerIssuer("linkerd", nil); err != nil {
+			t.Fatal(err)
+		}
+		if m := cluster.mutations(); len(m) > 0 {
+			t.Errorf("got requests %v", m)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package linkerd
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/adapter"
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// certificateWatcher periodically checks the validity of the trust anchors
+// and the identity issuer and streams events when they are about to expire
+type certificateWatcher struct {
+	namespace  string
+	interval   time.Duration
+	thresholds []time.Duration // sorted from the largest to the smallest
+
+	once sync.Once
+	// notified records the smallest threshold already reported per certificate
+	notified map[string]time.Duration
+}
+
+// watchedCertificate is a certificate checked by the watcher
+type watchedCertificate struct {
+	role string
+	cert *x509.Certificate
+}
+
+func newCertificateWatcher(settings map[string]string) (*certificateWatcher, error) {
+	interval, err := time.ParseDuration(settings[internalconfig.CertWatchIntervalEnv])
+	if err != nil {
+		return nil, err
+	}
+	// time.NewTicker panics on non-positive intervals
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval %s is not positive", interval)
+	}
+
+	var thresholds []time.Duration
+	for _, t := range strings.Split(settings[internalconfig.CertWatchThresholdsEnv], ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return nil, fmt.Errorf("empty threshold in %q", settings[internalconfig.CertWatchThresholdsEnv])
+		}
+		d, err := time.ParseDuration(t)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("threshold %s is not positive", d)
+		}
+		thresholds = append(thresholds, d)
+	}
+	sort.Slice(thresholds, func(i, j int) bool {
+		return thresholds[i] > thresholds[j]
+	})
+
+	return &certificateWatcher{
+		namespace:  settings[internalconfig.CertWatchNamespaceEnv],
+		interval:   interval,
+		thresholds: thresholds,
+		notified:   map[string]time.Duration{},
+	}, nil
+}
+
+// watchCertificates checks the certificates every interval, it runs for the lifetime of the adapter
+func (linkerd *Linkerd) watchCertificates() {
+	ticker := time.NewTicker(linkerd.certWatcher.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := linkerd.snapshot().checkCertificates(); err != nil {
+			linkerd.Log.Error(err)
+		}
+		<-ticker.C
+	}
+}
+
+// checkCertificates streams an event for every certificate which crossed a threshold
+// since the last check and records the next expiry in the mesh status
+func (linkerd *Linkerd) checkCertificates() error {
+	w := linkerd.certWatcher
+	certs, err := linkerd.getIdentityCertificates(w.namespace)
+	if err != nil {
+		return ErrCertificateExpiry(err)
+	}
+
+	var next time.Time
+	for _, c := range certs {
+		if next.IsZero() || c.cert.NotAfter.Before(next) {
+			next = c.cert.NotAfter
+		}
+
+		remaining := time.Until(c.cert.NotAfter)
+		threshold, crossed := w.crossedThreshold(remaining)
+		if !crossed {
+			continue
+		}
+		fingerprint := sha256.Sum256(c.cert.Raw)
+		id := hex.EncodeToString(fingerprint[:])
+		if last, ok := w.notified[id]; ok && last <= threshold {
+			continue
+		}
+		w.notified[id] = threshold
+
+		e := &adapter.Event{
+			Summary: fmt.Sprintf("Linkerd %s certificate expires in %s", c.role, humanizeDuration(remaining)),
+			Details: fmt.Sprintf("Certificate %s in namespace %s is valid until %s", c.cert.Subject.CommonName, w.namespace, c.cert.NotAfter.Format(time.RFC3339)),
+		}
+		if remaining <= 0 {
+			e.Summary = fmt.Sprintf("Linkerd %s certificate has expired", c.role)
+		}
+		if remaining <= w.thresholds[len(w.thresholds)-1] {
+			linkerd.StreamErr(e, ErrCertificateExpiry(fmt.Errorf("%s", e.Summary)))
+		} else {
+			linkerd.StreamInfo(e)
+		}
+	}
+
+	linkerd.Config.SetKey(fmt.Sprintf("%s.%s", adapter.MeshSpecKey, internalconfig.CertificateExpiry), next.Format(time.RFC3339))
+	return nil
+}
+
+// crossedThreshold returns the smallest threshold the remaining validity is below of
+func (w *certificateWatcher) crossedThreshold(remaining time.Duration) (time.Duration, bool) {
+	for i := len(w.thresholds) - 1; i >= 0; i-- {
+		if remaining <= w.thresholds[i] {
+			return w.thresholds[i], true
+		}
+	}
+	return 0, false
+}
+
+// getIdentityCertificates returns the trust anchors and the issuer certificate of the control plane
+func (linkerd *Linkerd) getIdentityCertificates(namespace string) ([]watchedCertificate, error) {
+	anchors, err := linkerd.getTrustAnchors(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := linkerd.KubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), identityIssuerSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	certKey, _ := issuerSecretKeys(secret)
+	issuer, err := parseCertificates(secret.Data[certKey])
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []watchedCertificate{{role: "issuer", cert: issuer[0]}}
+	for _, anchor := range anchors {
+		certs = append(certs, watchedCertificate{role: "trust anchor", cert: anchor})
+	}
+	return certs, nil
+}
+
+// humanizeDuration formats a duration in days and hours
+func humanizeDuration(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days == 0 {
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dd%dh", days, hours)
+}
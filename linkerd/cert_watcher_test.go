@@ -0,0 +1,90 @@
+package linkerd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+)
+
+func TestNewCertificateWatcher(t *testing.T) {
+	tests := []struct {
+		name       string
+		interval   string
+		thresholds string
+		want       []time.Duration
+	}{
+		{name: "defaults", interval: "1h", thresholds: "720h,168h,24h", want: []time.Duration{720 * time.Hour, 168 * time.Hour, 24 * time.Hour}},
+		{name: "unsorted", interval: "1h", thresholds: "24h, 720h", want: []time.Duration{720 * time.Hour, 24 * time.Hour}},
+		{name: "invalid interval", interval: "hourly", thresholds: "24h"},
+		{name: "zero interval", interval: "0s", thresholds: "24h"},
+		{name: "empty threshold", interval: "1h", thresholds: "720h,,24h"},
+		{name: "negative threshold", interval: "1h", thresholds: "-24h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := newCertificateWatcher(map[string]string{
+				internalconfig.CertWatchNamespaceEnv:  "linkerd",
+				internalconfig.CertWatchIntervalEnv:   tt.interval,
+				internalconfig.CertWatchThresholdsEnv: tt.thresholds,
+			})
+			if tt.want == nil {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(w.thresholds, tt.want) {
+				t.Errorf("got thresholds %v, want %v", w.thresholds, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrossedThreshold(t *testing.T) {
+	w, err := newCertificateWatcher(internalconfig.CertificateWatch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remaining time.Duration
+		threshold time.Duration
+		crossed   bool
+	}{
+		{remaining: 1000 * time.Hour},
+		{remaining: 700 * time.Hour, threshold: 720 * time.Hour, crossed: true},
+		{remaining: 168 * time.Hour, threshold: 168 * time.Hour, crossed: true},
+		{remaining: time.Hour, threshold: 24 * time.Hour, crossed: true},
+		{remaining: -time.Hour, threshold: 24 * time.Hour, crossed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remaining.String(), func(t *testing.T) {
+			threshold, crossed := w.crossedThreshold(tt.remaining)
+			if threshold != tt.threshold || crossed != tt.crossed {
+				t.Errorf("got %s, %t, want %s, %t", threshold, crossed, tt.threshold, tt.crossed)
+			}
+		})
+	}
+}
+
+func TestHumanizeDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Hour:                  "0h",
+		90 * time.Minute:            "1h",
+		26 * time.Hour:              "1d2h",
+		30*24*time.Hour + time.Hour: "30d1h",
+	}
+
+	for d, want := range tests {
+		if got := humanizeDuration(d); got != want {
+			t.Errorf("humanizeDuration(%s) = %s, want %s", d, got, want)
+		}
+	}
+}
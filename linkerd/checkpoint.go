@@ -0,0 +1,78 @@
+package linkerd
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	checkpointPrefix = "meshery-linkerd-"
+	checkpointKey    = "checkpoint"
+)
+
+// Checkpoints record the progress of multi step operations in a configmap
+// inside the cluster so that an operation interrupted by a crash of the
+// adapter can be resumed from the last completed step
+
+// loadCheckpoint reads the checkpoint with the given name into v,
+// it returns false if there is no checkpoint
+func (linkerd *Linkerd) loadCheckpoint(namespace, name string, v interface{}) (bool, error) {
+	cm, err := linkerd.KubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), checkpointPrefix+name, metav1.GetOptions{})
+	if kubeerror.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrCheckpoint(err)
+	}
+
+	if err = json.Unmarshal([]byte(cm.Data[checkpointKey]), v); err != nil {
+		return false, ErrCheckpoint(err)
+	}
+	return true, nil
+}
+
+// saveCheckpoint creates or updates the checkpoint with the given name
+func (linkerd *Linkerd) saveCheckpoint(namespace, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ErrCheckpoint(err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      checkpointPrefix + name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "meshery",
+			},
+		},
+		Data: map[string]string{
+			checkpointKey: string(data),
+		},
+	}
+
+	client := linkerd.KubeClient.CoreV1().ConfigMaps(namespace)
+	_, err = client.Update(context.TODO(), cm, metav1.UpdateOptions{})
+	if kubeerror.IsNotFound(err) {
+		_, err = client.Create(context.TODO(), cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return ErrCheckpoint(err)
+	}
+
+	return nil
+}
+
+// clearCheckpoint removes the checkpoint with the given name if it exists
+func (linkerd *Linkerd) clearCheckpoint(namespace, name string) error {
+	err := linkerd.KubeClient.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), checkpointPrefix+name, metav1.DeleteOptions{})
+	if err != nil && !kubeerror.IsNotFound(err) {
+		return ErrCheckpoint(err)
+	}
+
+	return nil
+}
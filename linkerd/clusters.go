@@ -0,0 +1,168 @@
+package linkerd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/adapter"
+	"github.com/layer5io/meshery-adapter-library/status"
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	mesherykube "github.com/layer5io/meshkit/utils/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// clusterSet holds the clients of the kubeconfig contexts targeted by
+// operations, which are built on first use, and the mesh status of each cluster
+type clusterSet struct {
+	mu       sync.Mutex
+	clients  map[string]*mesherykube.Client
+	statuses map[string]ClusterStatus
+
+	// reload guards the clients of the handler itself, which
+	// are swapped when the kubeconfig is reloaded
+	reload        sync.RWMutex
+	kubeconfigSum [sha256.Size]byte
+	source        internalconfig.KubeconfigSource
+}
+
+// ClusterStatus is the mesh status of a single cluster
+type ClusterStatus struct {
+	Status    string `json:"status"`
+	Version   string `json:"version"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// operationTarget is the part of an operation body selecting the cluster it is applied to
+type operationTarget struct {
+	// Context is a context of the kubeconfig in use,
+	// the current context is used if it is empty
+	Context string `json:"context,omitempty"`
+}
+
+func newClusterSet() *clusterSet {
+	return &clusterSet{
+		clients:  map[string]*mesherykube.Client{},
+		statuses: map[string]ClusterStatus{},
+	}
+}
+
+// reset drops the clients built so far
+func (cs *clusterSet) reset() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.clients = map[string]*mesherykube.Client{}
+}
+
+// snapshot returns a copy of the handler, which keeps its clients
+// even if the kubeconfig is reloaded while it is in use
+func (linkerd *Linkerd) snapshot() *Linkerd {
+	linkerd.clusters.reload.RLock()
+	defer linkerd.clusters.reload.RUnlock()
+
+	l := *linkerd
+	return &l
+}
+
+// forOperation returns the handler for the cluster targeted by the operation
+func (linkerd *Linkerd) forOperation(opReq adapter.OperationRequest) (*Linkerd, error) {
+	target := operationTarget{}
+	// The body is not necessarily an options document, e.g. for custom
+	// operations, hence it only selects a cluster if it can be parsed
+	if err := parseOperationBody(opReq.CustomBody, &target); err != nil || target.Context == "" {
+		return linkerd.snapshot(), nil
+	}
+
+	return linkerd.forContext(target.Context)
+}
+
+// forContext returns a copy of the handler whose clients talk to the cluster of
+// the named context of the kubeconfig in use, the removal of the faults injected
+// into that cluster before the adapter restarted is resumed on first use
+func (linkerd *Linkerd) forContext(name string) (*Linkerd, error) {
+	client, err := linkerd.clusters.client(name, linkerd.kubeconfigSource())
+	if err != nil {
+		return nil, ErrKubeContext(err, name)
+	}
+
+	l := linkerd.snapshot()
+	l.contextName = name
+	l.MesheryKubeclient = client
+	l.KubeClient = client.KubeClient
+	l.DynamicKubeClient = client.DynamicKubeClient
+	l.RestConfig = client.RestConfig
+	l.resumeFaultRemovalsOnce()
+	return l, nil
+}
+
+// kubeconfigSource returns where the kubernetes credentials of the handler come from
+func (linkerd *Linkerd) kubeconfigSource() internalconfig.KubeconfigSource {
+	linkerd.clusters.reload.RLock()
+	defer linkerd.clusters.reload.RUnlock()
+
+	return linkerd.clusters.source
+}
+
+// client returns the client of the named context of the kubeconfig the credentials come from, building it if needed
+func (cs *clusterSet) client(name string, source internalconfig.KubeconfigSource) (*mesherykube.Client, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if client, ok := cs.clients[name]; ok {
+		return client, nil
+	}
+
+	if source.Path == "" {
+		return nil, fmt.Errorf("kubeconfig contexts are not available with the %s", source.Name)
+	}
+	cfg, err := clientcmd.LoadFromFile(source.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := cfg.Contexts[name]; !ok {
+		return nil, fmt.Errorf("context not found in kubeconfig")
+	}
+	cfg.CurrentContext = name
+
+	kubeconfig, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mesherykube.New(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	cs.clients[name] = client
+	return client, nil
+}
+
+// currentContext returns the kubeconfig context the handler operates on
+func (linkerd *Linkerd) currentContext() string {
+	if linkerd.contextName != "" {
+		return linkerd.contextName
+	}
+	if linkerd.ClientcmdConfig != nil && linkerd.ClientcmdConfig.CurrentContext != "" {
+		return linkerd.ClientcmdConfig.CurrentContext
+	}
+	return status.Default
+}
+
+// recordClusterStatus records the mesh status of the cluster the handler
+// operates on and persists the status of all clusters in the adapter config
+func (linkerd *Linkerd) recordClusterStatus(st, version string) {
+	cs := linkerd.clusters
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.statuses[linkerd.currentContext()] = ClusterStatus{
+		Status:    st,
+		Version:   version,
+		UpdatedAt: time.Now().Format(time.RFC3339),
+	}
+	if err := linkerd.Config.SetObject(internalconfig.ClustersKey, cs.statuses); err != nil {
+		linkerd.Log.Warn(err)
+	}
+}
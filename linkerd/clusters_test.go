@@ -0,0 +1,96 @@
+package linkerd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/layer5io/meshery-adapter-library/adapter"
+	"github.com/layer5io/meshery-adapter-library/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestForOperation(t *testing.T) {
+	linkerd, _ := newFakeLinkerd(t)
+	east, _ := newFakeLinkerd(t)
+	linkerd.clusters.clients["east"] = east.MesheryKubeclient
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "east-only"}}
+	if _, err := east.KubeClient.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		context string
+		valid   bool
+	}{
+		{name: "current context", body: `{"namespaces": ["books"]}`, valid: true},
+		{name: "custom manifest", body: "apiVersion: v1\nkind: Namespace\n---\nkind: Service", valid: true},
+		{name: "named context", body: `{"context": "east"}`, context: "east", valid: true},
+		{name: "unknown context", body: `{"context": "west"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := linkerd.forOperation(adapter.OperationRequest{CustomBody: tt.body})
+			if !tt.valid {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.contextName != tt.context {
+				t.Errorf("got context %q, want %q", l.contextName, tt.context)
+			}
+
+			_, err = l.KubeClient.CoreV1().Namespaces().Get(context.TODO(), "east-only", metav1.GetOptions{})
+			if onEast := err == nil; onEast != (tt.context == "east") {
+				t.Errorf("handler talks to the cluster of context east: %t", onEast)
+			}
+		})
+	}
+}
+
+func TestCurrentContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		linkerd *Linkerd
+		want    string
+	}{
+		{name: "named context", linkerd: &Linkerd{contextName: "east", Adapter: adapter.Adapter{ClientcmdConfig: &api.Config{CurrentContext: "west"}}}, want: "east"},
+		{name: "kubeconfig", linkerd: &Linkerd{Adapter: adapter.Adapter{ClientcmdConfig: &api.Config{CurrentContext: "west"}}}, want: "west"},
+		{name: "no kubeconfig", linkerd: &Linkerd{}, want: status.Default},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.linkerd.currentContext(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	linkerd, _ := newFakeLinkerd(t)
+	reloaded, _ := newFakeLinkerd(t)
+	linkerd.clusters.clients["east"] = reloaded.MesheryKubeclient
+
+	snapshot := linkerd.snapshot()
+	linkerd.KubeClient = reloaded.KubeClient
+	linkerd.MesheryKubeclient = reloaded.MesheryKubeclient
+	linkerd.clusters.reset()
+
+	if snapshot.KubeClient == linkerd.KubeClient || snapshot.MesheryKubeclient == linkerd.MesheryKubeclient {
+		t.Error("snapshot picked up the reloaded clients")
+	}
+	if len(linkerd.clusters.clients) != 0 {
+		t.Errorf("got %d clients of other contexts after the reset", len(linkerd.clusters.clients))
+	}
+}
@@ -0,0 +1,60 @@
+package linkerd
+
+import (
+	"fmt"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+)
+
+const (
+	defaultCNINamespace = "linkerd-cni"
+	cniDaemonSet        = "linkerd-cni"
+)
+
+// installCNI installs or removes the linkerd CNI plugin and waits for its
+// DaemonSet to be ready on every node before reporting success
+func (linkerd *Linkerd) installCNI(del bool, version, namespace string) (string, error) {
+	st := status.Installing
+	if del {
+		st = status.Removing
+	}
+	if namespace == "" {
+		namespace = defaultCNINamespace
+	}
+
+	manifest, err := linkerd.runLinkerdCLI(version, "install-cni", "--cni-namespace", namespace)
+	if err != nil {
+		return st, ErrInstallCNI(err)
+	}
+
+	if err = linkerd.applyManifest([]byte(manifest), del, namespace); err != nil {
+		return st, ErrInstallCNI(err)
+	}
+
+	if del {
+		return status.Removed, nil
+	}
+
+	if err = linkerd.waitForWorkload(workload{Kind: daemonSetKind, Namespace: namespace, Name: cniDaemonSet}, defaultRolloutTimeout); err != nil {
+		return st, ErrInstallCNI(err)
+	}
+	return status.Installed, nil
+}
+
+// checkCNIReady makes sure the CNI plugin runs on every node, otherwise proxies
+// of a control plane installed in CNI mode would start without their iptables rules
+func (linkerd *Linkerd) checkCNIReady(namespace string) error {
+	if namespace == "" {
+		namespace = defaultCNINamespace
+	}
+
+	ready, err := linkerd.isWorkloadReady(workload{Kind: daemonSetKind, Namespace: namespace, Name: cniDaemonSet})
+	if err != nil {
+		return ErrCNINotReady(err)
+	}
+	if !ready {
+		return ErrCNINotReady(fmt.Errorf("DaemonSet %s/%s is not ready on all nodes", namespace, cniDaemonSet))
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package linkerd
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckCNIReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		daemonSet *appsv1.DaemonSet
+		ready     bool
+	}{
+		{
+			name: "ready on every node",
+			daemonSet: &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: cniDaemonSet, Generation: 2},
+				Status:     appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+			},
+			ready: true,
+		},
+		{
+			name: "unavailable on a node",
+			daemonSet: &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: cniDaemonSet, Generation: 2},
+				Status:     appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2},
+			},
+		},
+		{
+			name: "rollout not observed",
+			daemonSet: &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: cniDaemonSet, Generation: 3},
+				Status:     appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+			},
+		},
+		{
+			name: "not installed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkerd, _ := newFakeLinkerd(t)
+			if tt.daemonSet != nil {
+				if _, err := linkerd.KubeClient.AppsV1().DaemonSets(defaultCNINamespace).Create(context.TODO(), tt.daemonSet, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := linkerd.checkCNIReady("")
+			if tt.ready && err != nil {
+				t.Error(err)
+			}
+			if !tt.ready && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
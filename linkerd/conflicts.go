@@ -0,0 +1,161 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// meshMarker is a label or annotation enabling the injection of another mesh
+type meshMarker struct {
+	mesh string
+	key  string
+	// values enabling the injection, any value if empty
+	values []string
+}
+
+// namespaceMarkers enable the injection of another mesh for a whole namespace
+var namespaceMarkers = []meshMarker{
+	{mesh: "Istio", key: "istio-injection", values: []string{"enabled"}},
+	{mesh: "Istio", key: "istio.io/rev"},
+	{mesh: "Consul", key: "consul.hashicorp.com/connect-inject", values: []string{"true"}},
+	{mesh: "Kuma", key: "kuma.io/sidecar-injection", values: []string{"enabled", "true"}},
+}
+
+// podMarkers enable the injection of another mesh for the pods of a workload
+var podMarkers = []meshMarker{
+	{mesh: "Istio", key: "sidecar.istio.io/inject", values: []string{"true"}},
+	{mesh: "Istio", key: "istio.io/rev"},
+	{mesh: "Consul", key: "consul.hashicorp.com/connect-inject", values: []string{"true"}},
+	{mesh: "Kuma", key: "kuma.io/sidecar-injection", values: []string{"enabled", "true"}},
+}
+
+// foreignSidecars are the names of the sidecar containers injected by other meshes
+var foreignSidecars = map[string]string{
+	"istio-proxy":   "Istio",
+	"envoy-sidecar": "Consul",
+	"kuma-sidecar":  "Kuma",
+}
+
+// match returns a description of the marker if it is set in the labels or annotations
+func (m meshMarker) match(labels, annotations map[string]string) (string, bool) {
+	for _, set := range []map[string]string{labels, annotations} {
+		value, ok := set[m.key]
+		if !ok {
+			continue
+		}
+		if len(m.values) == 0 || contains(m.values, value) {
+			return fmt.Sprintf("%s marker %s=%s", m.mesh, m.key, value), true
+		}
+	}
+	return "", false
+}
+
+// namespaceConflicts returns the markers of other meshes on the namespace and its
+// pods as well as the pods already running the sidecar of another mesh
+func (linkerd *Linkerd) namespaceConflicts(ns *corev1.Namespace) ([]string, error) {
+	conflicts := []string{}
+	for _, m := range namespaceMarkers {
+		if c, ok := m.match(ns.Labels, ns.Annotations); ok {
+			conflicts = append(conflicts, fmt.Sprintf("namespace %s has %s", ns.Name, c))
+		}
+	}
+
+	pods, err := linkerd.KubeClient.CoreV1().Pods(ns.Name).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return append(conflicts, podConflicts(pods.Items)...), nil
+}
+
+// workloadConflicts returns the markers of other meshes on the pod template of
+// the workload and the pods of the workload running the sidecar of another mesh
+func (linkerd *Linkerd) workloadConflicts(w workload) ([]string, error) {
+	conflicts := []string{}
+	template, err := linkerd.podTemplate(w)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range podMarkers {
+		if c, ok := m.match(template.Labels, template.Annotations); ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s has %s", w, c))
+		}
+	}
+
+	// Cronjobs have no pods running permanently
+	if w.Kind == cronJobKind {
+		return conflicts, nil
+	}
+	pods, err := linkerd.workloadPods(w)
+	if err != nil {
+		return nil, err
+	}
+	return append(conflicts, podConflicts(pods)...), nil
+}
+
+// podConflicts returns the pods carrying the markers or the sidecar of another mesh
+func podConflicts(pods []corev1.Pod) []string {
+	conflicts := []string{}
+	for i := range pods {
+		pod := &pods[i]
+		for _, m := range podMarkers {
+			if c, ok := m.match(pod.Labels, pod.Annotations); ok {
+				conflicts = append(conflicts, fmt.Sprintf("pod %s/%s has %s", pod.Namespace, pod.Name, c))
+			}
+		}
+		containers := []corev1.Container{}
+		containers = append(containers, pod.Spec.InitContainers...)
+		containers = append(containers, pod.Spec.Containers...)
+		for _, c := range containers {
+			if mesh, ok := foreignSidecars[c.Name]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("pod %s/%s runs the %s sidecar %s", pod.Namespace, pod.Name, mesh, c.Name))
+			}
+		}
+	}
+
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// checkNamespaceConflicts fails with ErrMeshConflict if another mesh injects its sidecar into the namespace
+func (linkerd *Linkerd) checkNamespaceConflicts(namespace string) error {
+	ns, err := linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	conflicts, err := linkerd.namespaceConflicts(ns)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return ErrMeshConflict(fmt.Sprintf("namespace %s", namespace), conflicts)
+	}
+	return nil
+}
+
+// checkWorkloadConflicts fails with ErrMeshConflict if another mesh injects its sidecar into the workload
+func (linkerd *Linkerd) checkWorkloadConflicts(w workload) error {
+	conflicts, err := linkerd.workloadConflicts(w)
+	if err != nil {
+		return err
+	}
+
+	// Markers of the namespace apply to the workload as well
+	ns, err := linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), w.Namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for _, m := range namespaceMarkers {
+		if c, ok := m.match(ns.Labels, ns.Annotations); ok {
+			conflicts = append(conflicts, fmt.Sprintf("namespace %s has %s", ns.Name, c))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return ErrMeshConflict(w.String(), conflicts)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package linkerd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/layer5io/meshkit/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMeshMarkerMatch(t *testing.T) {
+	rev := meshMarker{mesh: "Istio", key: "istio.io/rev"}
+	inject := meshMarker{mesh: "Istio", key: "sidecar.istio.io/inject", values: []string{"true"}}
+
+	tests := []struct {
+		name        string
+		marker      meshMarker
+		labels      map[string]string
+		annotations map[string]string
+		match       bool
+	}{
+		{name: "any value", marker: rev, labels: map[string]string{"istio.io/rev": "canary"}, match: true},
+		{name: "enabling value", marker: inject, annotations: map[string]string{"sidecar.istio.io/inject": "true"}, match: true},
+		{name: "disabling value", marker: inject, annotations: map[string]string{"sidecar.istio.io/inject": "false"}},
+		{name: "not set", marker: rev, labels: map[string]string{"app": "books"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, match := tt.marker.match(tt.labels, tt.annotations); match != tt.match {
+				t.Errorf("got %t, want %t", match, tt.match)
+			}
+		})
+	}
+}
+
+func TestPodConflicts(t *testing.T) {
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "bookapp", Name: "books"}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "books"}, {Name: proxyContainerName}}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "bookapp", Name: "authors"}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "authors"}, {Name: "istio-proxy"}}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "bookapp", Name: "webapp", Labels: map[string]string{"kuma.io/sidecar-injection": "enabled"}}},
+	}
+
+	want := []string{
+		"pod bookapp/authors runs the Istio sidecar istio-proxy",
+		"pod bookapp/webapp has Kuma marker kuma.io/sidecar-injection=enabled",
+	}
+	if got := podConflicts(pods); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLoadToMeshConflicts(t *testing.T) {
+	tests := []struct {
+		name           string
+		namespace      map[string]string
+		template       map[string]string
+		allowConflicts bool
+		conflict       bool
+	}{
+		{name: "no other mesh"},
+		{name: "pod template marker", template: map[string]string{"sidecar.istio.io/inject": "true"}, conflict: true},
+		{name: "namespace marker", namespace: map[string]string{"istio-injection": "enabled"}, conflict: true},
+		{name: "conflicts allowed", template: map[string]string{"sidecar.istio.io/inject": "true"}, allowConflicts: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkerd, cluster := newFakeLinkerd(t)
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bookapp", Labels: tt.namespace}}
+			if _, err := linkerd.KubeClient.CoreV1().Namespaces().Create(context.TODO(), ns, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			labels := map[string]string{"app": "books"}
+			for k, v := range tt.template {
+				labels[k] = v
+			}
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "books"},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "books"}},
+					Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+				},
+			}
+			if _, err := linkerd.KubeClient.AppsV1().Deployments("bookapp").Create(context.TODO(), deployment, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := linkerd.LoadToMesh("bookapp", "books", false, tt.allowConflicts)
+			if tt.conflict {
+				if errors.GetCode(err) != ErrMeshConflictCode {
+					t.Errorf("got %v, want ErrMeshConflict", err)
+				}
+				if m := cluster.mutations(); len(m) > 2 {
+					t.Errorf("deployment was annotated: %v", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := linkerd.KubeClient.AppsV1().Deployments("bookapp").Get(context.TODO(), "books", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Spec.Template.Annotations[injectAnnotation] != injectEnabled {
+				t.Errorf("got annotations %v", d.Spec.Template.Annotations)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@
 package linkerd
 
 import (
+	"strings"
+
 	"github.com/layer5io/meshkit/errors"
 )
 
@@ -28,9 +30,76 @@ var (
 	ErrCustomOperationCode = "1012"
 	// ErrOpInvalidCode is the error code for ErrOpInvalid
 	ErrOpInvalidCode = "1013"
+	// ErrParseOperationBodyCode is the error code for ErrParseOperationBody
+	ErrParseOperationBodyCode = "1014"
+	// ErrIdentityCertificateCode is the error code for ErrIdentityCertificate
+	ErrIdentityCertificateCode = "1015"
+	// ErrRolloutCode is the error code for ErrRollout
+	ErrRolloutCode = "1016"
+	// ErrPortForwardCode is the error code for ErrPortForward
+	ErrPortForwardCode = "1017"
+	// ErrCheckpointCode is the error code for ErrCheckpoint
+	ErrCheckpointCode = "1018"
+	// ErrTrustAnchorRotationCode is the error code for ErrTrustAnchorRotation
+	ErrTrustAnchorRotationCode = "1019"
+	// ErrCertificateExpiryCode is the error code for ErrCertificateExpiry
+	ErrCertificateExpiryCode = "1020"
+	// ErrCertManagerCode is the error code for ErrCertManager
+	ErrCertManagerCode = "1021"
+	// ErrCertManagerNotFoundCode is the error code for ErrCertManagerNotFound
+	ErrCertManagerNotFoundCode = "1022"
+	// ErrInstallCNICode is the error code for ErrInstallCNI
+	ErrInstallCNICode = "1023"
+	// ErrCNINotReadyCode is the error code for ErrCNINotReady
+	ErrCNINotReadyCode = "1024"
+	// ErrInstallExtensionCode is the error code for ErrInstallExtension
+	ErrInstallExtensionCode = "1025"
+	// ErrControlPlaneNotFoundCode is the error code for ErrControlPlaneNotFound
+	ErrControlPlaneNotFoundCode = "1026"
+	// ErrKubeContextCode is the error code for ErrKubeContext
+	ErrKubeContextCode = "1027"
+	// ErrMulticlusterLinkCode is the error code for ErrMulticlusterLink
+	ErrMulticlusterLinkCode = "1028"
+	// ErrExportServiceCode is the error code for ErrExportService
+	ErrExportServiceCode = "1029"
+	// ErrKubeconfigReloadCode is the error code for ErrKubeconfigReload
+	ErrKubeconfigReloadCode = "1030"
+	// ErrPermissionsCode is the error code for ErrPermissions
+	ErrPermissionsCode = "1032"
+	// ErrInjectWorkloadsCode is the error code for ErrInjectWorkloads
+	ErrInjectWorkloadsCode = "1033"
+	// ErrMeshStatusCode is the error code for ErrMeshStatus
+	ErrMeshStatusCode = "1034"
+	// ErrProxyUpgradeCode is the error code for ErrProxyUpgrade
+	ErrProxyUpgradeCode = "1035"
+	// ErrProxyConfigCode is the error code for ErrProxyConfig
+	ErrProxyConfigCode = "1036"
+	// ErrAnnotateNamespaceCode is the error code for ErrAnnotateNamespace
+	ErrAnnotateNamespaceCode = "1037"
+	// ErrMeshConflictCode is the error code for ErrMeshConflict
+	ErrMeshConflictCode = "1038"
+	// ErrServiceProfileCode is the error code for ErrServiceProfile
+	ErrServiceProfileCode = "1039"
+	// ErrRoutePolicyCode is the error code for ErrRoutePolicy
+	ErrRoutePolicyCode = "1040"
+	// ErrPrometheusCode is the error code for ErrPrometheus
+	ErrPrometheusCode = "1041"
+	// ErrCanaryCode is the error code for ErrCanary
+	ErrCanaryCode = "1042"
+	// ErrFaultInjectionCode is the error code for ErrFaultInjection
+	ErrFaultInjectionCode = "1043"
+	// ErrPolicyCode is the error code for ErrPolicy
+	ErrPolicyCode = "1044"
+	// ErrMTLSVerifyCode is the error code for ErrMTLSVerify
+	ErrMTLSVerifyCode = "1045"
+	// ErrStatsCode is the error code for ErrStats
+	ErrStatsCode = "1046"
 
 	// ErrOpInvalid is the error for invalid operation
 	ErrOpInvalid = errors.New(ErrOpInvalidCode, errors.Alert, []string{"Invalid operation"}, []string{}, []string{}, []string{})
+
+	// ErrCertManagerNotFound is the error for missing cert-manager CRDs
+	ErrCertManagerNotFound = errors.New(ErrCertManagerNotFoundCode, errors.Alert, []string{"cert-manager is not installed"}, []string{"The cert-manager.io/v1 Issuer and Certificate CRDs were not found in the cluster"}, []string{}, []string{"Install cert-manager before installing Linkerd with the cert-manager option"})
 )
 
 // ErrInstallLinkerd is the error for install mesh
@@ -82,3 +151,158 @@ func ErrSampleApp(err error) error {
 func ErrCustomOperation(err error) error {
 	return errors.New(ErrCustomOperationCode, errors.Alert, []string{"Error with custom operation: ", err.Error()}, []string{}, []string{}, []string{})
 }
+
+// ErrParseOperationBody is the error for an invalid operation body
+func ErrParseOperationBody(err error) error {
+	return errors.New(ErrParseOperationBodyCode, errors.Alert, []string{"Error parsing operation body: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrIdentityCertificate is the error for identity certificate management
+func ErrIdentityCertificate(err error) error {
+	return errors.New(ErrIdentityCertificateCode, errors.Alert, []string{"Error managing identity certificates: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrRollout is the error for restarting a workload
+func ErrRollout(err error, des string) error {
+	return errors.New(ErrRolloutCode, errors.Alert, []string{"Error rolling out workload: ", des}, []string{err.Error()}, []string{}, []string{})
+}
+
+// ErrPortForward is the error for port forwarding to a pod
+func ErrPortForward(err error, des string) error {
+	return errors.New(ErrPortForwardCode, errors.Alert, []string{"Error port forwarding to pod: ", des}, []string{err.Error()}, []string{}, []string{})
+}
+
+// ErrCheckpoint is the error for persisting the progress of an operation
+func ErrCheckpoint(err error) error {
+	return errors.New(ErrCheckpointCode, errors.Alert, []string{"Error persisting operation progress: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrTrustAnchorRotation is the error for a failed trust anchor rotation phase
+func ErrTrustAnchorRotation(err error, des string) error {
+	return errors.New(ErrTrustAnchorRotationCode, errors.Alert, []string{"Error rotating trust anchor in phase: ", des}, []string{err.Error()}, []string{}, []string{})
+}
+
+// ErrCertificateExpiry is the error for identity certificates about to expire
+func ErrCertificateExpiry(err error) error {
+	return errors.New(ErrCertificateExpiryCode, errors.Alert, []string{"Error checking certificate expiry: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrCertManager is the error for setting up cert-manager to manage the identity issuer
+func ErrCertManager(err error) error {
+	return errors.New(ErrCertManagerCode, errors.Alert, []string{"Error setting up cert-manager issuer: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrInstallCNI is the error for installing the linkerd CNI plugin
+func ErrInstallCNI(err error) error {
+	return errors.New(ErrInstallCNICode, errors.Alert, []string{"Error with Linkerd CNI operation: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrCNINotReady is the error for installing the control plane in CNI mode without a ready CNI plugin
+func ErrCNINotReady(err error) error {
+	return errors.New(ErrCNINotReadyCode, errors.Alert, []string{"Linkerd CNI plugin is not ready: ", err.Error()}, []string{}, []string{}, []string{"Run the linkerd-cni operation and wait for it to complete before installing the control plane"})
+}
+
+// ErrInstallExtension is the error for installing a linkerd extension
+func ErrInstallExtension(err error, des string) error {
+	return errors.New(ErrInstallExtensionCode, errors.Alert, []string{"Error with Linkerd extension operation: ", des}, []string{err.Error()}, []string{}, []string{})
+}
+
+// ErrControlPlaneNotFound is the error for operations requiring an installed control plane
+func ErrControlPlaneNotFound(namespace string) error {
+	return errors.New(ErrControlPlaneNotFoundCode, errors.Alert, []string{"Linkerd control plane not found in namespace: ", namespace}, []string{}, []string{}, []string{"Install the Linkerd control plane first"})
+}
+
+// ErrKubeContext is the error for creating clients for a kubeconfig context
+func ErrKubeContext(err error, des string) error {
+	return errors.New(ErrKubeContextCode, errors.Alert, []string{"Error creating clients for kubeconfig context: ", des}, []string{err.Error()}, []string{}, []string{})
+}
+
+// ErrMulticlusterLink is the error for linking clusters
+func ErrMulticlusterLink(err error) error {
+	return errors.New(ErrMulticlusterLinkCode, errors.Alert, []string{"Error linking clusters: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrExportService is the error for exporting services to linked clusters
+func ErrExportService(err error) error {
+	return errors.New(ErrExportServiceCode, errors.Alert, []string{"Error exporting services: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrKubeconfigReload is the error for reloading the kubeconfig
+func ErrKubeconfigReload(err error) error {
+	return errors.New(ErrKubeconfigReloadCode, errors.Alert, []string{"Error reloading kubeconfig: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrPermissions is the error for missing permissions of the kubernetes credentials in use
+func ErrPermissions(err error) error {
+	return errors.New(ErrPermissionsCode, errors.Alert, []string{"Kubernetes credentials lack permissions: ", err.Error()}, []string{}, []string{}, []string{"Apply the roles in install/kubernetes/rbac.yaml to the identity the adapter runs with"})
+}
+
+// ErrInjectWorkloads is the error for enabling proxy injection in workloads
+func ErrInjectWorkloads(err error) error {
+	return errors.New(ErrInjectWorkloadsCode, errors.Alert, []string{"Error injecting workloads: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrMeshStatus is the error for collecting the mesh coverage report
+func ErrMeshStatus(err error) error {
+	return errors.New(ErrMeshStatusCode, errors.Alert, []string{"Error collecting mesh status: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrProxyUpgrade is the error for upgrading the data plane proxies
+func ErrProxyUpgrade(err error) error {
+	return errors.New(ErrProxyUpgradeCode, errors.Alert, []string{"Error upgrading data plane proxies: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrProxyConfig is the error for configuring the proxy through annotations
+func ErrProxyConfig(err error) error {
+	return errors.New(ErrProxyConfigCode, errors.Alert, []string{"Error configuring proxies: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrAnnotateNamespace is the error for marking namespaces for proxy injection
+func ErrAnnotateNamespace(err error) error {
+	return errors.New(ErrAnnotateNamespaceCode, errors.Alert, []string{"Error annotating namespaces: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrMeshConflict is the error for injecting the proxy where another mesh injects its sidecar
+func ErrMeshConflict(target string, conflicts []string) error {
+	return errors.New(ErrMeshConflictCode, errors.Alert, []string{"Another service mesh injects its sidecar into ", target}, []string{strings.Join(conflicts, "; ")}, []string{"Meshing the pods with Linkerd as well results in two sidecars per pod"}, []string{"Remove the markers of the other mesh and restart the pods, or set allowConflicts to mesh them anyway"})
+}
+
+// ErrServiceProfile is the error for generating and applying service profiles
+func ErrServiceProfile(err error) error {
+	return errors.New(ErrServiceProfileCode, errors.Alert, []string{"Error with service profile operation: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrRoutePolicy is the error for patching the retry and timeout policies of service profile routes
+func ErrRoutePolicy(err error) error {
+	return errors.New(ErrRoutePolicyCode, errors.Alert, []string{"Error with route policy operation: ", err.Error()}, []string{}, []string{}, []string{"Generate the service profile with the service-profile operation first"})
+}
+
+// ErrPrometheus is the error for querying the Prometheus scraping the proxies
+func ErrPrometheus(err error) error {
+	return errors.New(ErrPrometheusCode, errors.Alert, []string{"Error querying Prometheus: ", err.Error()}, []string{}, []string{}, []string{"Install the viz extension or set prometheusURL to a Prometheus scraping the proxies"})
+}
+
+// ErrCanary is the error for shifting traffic to a canary
+func ErrCanary(err error) error {
+	return errors.New(ErrCanaryCode, errors.Alert, []string{"Error with canary operation: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrFaultInjection is the error for injecting faults into the traffic of a service
+func ErrFaultInjection(err error) error {
+	return errors.New(ErrFaultInjectionCode, errors.Alert, []string{"Error with fault injection operation: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrPolicy is the error for generating and applying authorization policies
+func ErrPolicy(err error) error {
+	return errors.New(ErrPolicyCode, errors.Alert, []string{"Error with authorization policy operation: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrMTLSVerify is the error for verifying that the traffic between the services is mutually authenticated
+func ErrMTLSVerify(err error) error {
+	return errors.New(ErrMTLSVerifyCode, errors.Alert, []string{"Error verifying mutual TLS: ", err.Error()}, []string{}, []string{}, []string{})
+}
+
+// ErrStats is the error for querying the golden metrics of the meshed deployments
+func ErrStats(err error) error {
+	return errors.New(ErrStatsCode, errors.Alert, []string{"Error querying stats: ", err.Error()}, []string{}, []string{}, []string{})
+}
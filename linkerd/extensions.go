@@ -0,0 +1,72 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// installExtension installs or removes a linkerd extension. The extension is rendered
+// by the CLI of the same version as the control plane installed in the namespace
+func (linkerd *Linkerd) installExtension(del bool, ext internalconfig.Extension, namespace string) (string, error) {
+	st := status.Installing
+	if del {
+		st = status.Removing
+	}
+	if namespace == "" {
+		namespace = defaultControlPlaneNamespace
+	}
+
+	version, err := linkerd.getControlPlaneVersion(namespace)
+	if kubeerror.IsNotFound(err) {
+		return st, ErrControlPlaneNotFound(namespace)
+	}
+	if err != nil {
+		return st, ErrInstallExtension(err, ext.Command)
+	}
+	if !ext.Supports(version) {
+		return st, ErrInstallExtension(fmt.Errorf("control plane version %s does not support the extension", version), ext.Command)
+	}
+
+	action := "install"
+	if del {
+		action = "uninstall"
+	}
+	manifest, err := linkerd.runLinkerdCLI(version, ext.Command, action, "--linkerd-namespace", namespace)
+	if err != nil {
+		return st, ErrInstallExtension(err, ext.Command)
+	}
+
+	// The extension resources carry their own namespace
+	if err = linkerd.applyManifest([]byte(manifest), del, ""); err != nil {
+		return st, ErrInstallExtension(err, ext.Command)
+	}
+
+	if del {
+		return status.Removed, nil
+	}
+
+	if err = linkerd.waitForDeployments(ext.Namespace); err != nil {
+		return st, ErrInstallExtension(err, ext.Command)
+	}
+	return status.Installed, nil
+}
+
+// waitForDeployments waits for all the deployments in the namespace to be ready
+func (linkerd *Linkerd) waitForDeployments(namespace string) error {
+	deploys, err := linkerd.KubeClient.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range deploys.Items {
+		if err = linkerd.waitForWorkload(workload{Kind: deploymentKind, Namespace: namespace, Name: d.Name}, defaultRolloutTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package linkerd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/layer5io/meshery-adapter-library/adapter"
+	"github.com/layer5io/meshkit/logger"
+	mesherykube "github.com/layer5io/meshkit/utils/kubernetes"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// fakeResource is a resource served by the fake cluster
+type fakeResource struct {
+	groupVersion string
+	resource     string
+	kind         string
+	namespaced   bool
+}
+
+var fakeResources = []fakeResource{
+	{"v1", "namespaces", "Namespace", false},
+	{"v1", "pods", "Pod", true},
+	{"v1", "services", "Service", true},
+	{"v1", "configmaps", "ConfigMap", true},
+	{"v1", "secrets", "Secret", true},
+	{"v1", "serviceaccounts", "ServiceAccount", true},
+	{"apps/v1", "deployments", "Deployment", true},
+	{"apps/v1", "statefulsets", "StatefulSet", true},
+	{"apps/v1", "daemonsets", "DaemonSet", true},
+	{"apps/v1", "replicasets", "ReplicaSet", true},
+	{"batch/v1", "jobs", "Job", true},
+	{"batch/v1", "cronjobs", "CronJob", true},
+	{"batch/v1beta1", "cronjobs", "CronJob", true},
+	{"admissionregistration.k8s.io/v1", "mutatingwebhookconfigurations", "MutatingWebhookConfiguration", false},
+	{"authorization.k8s.io/v1", "selfsubjectrulesreviews", "SelfSubjectRulesReview", false},
+	{"split.smi-spec.io/v1alpha1", "trafficsplits", "TrafficSplit", true},
+	{"linkerd.io/v1alpha2", "serviceprofiles", "ServiceProfile", true},
+	{"policy.linkerd.io/v1beta1", "servers", "Server", true},
+	{"policy.linkerd.io/v1beta1", "serverauthorizations", "ServerAuthorization", true},
+	{"policy.linkerd.io/v1alpha1", "authorizationpolicies", "AuthorizationPolicy", true},
+	{"policy.linkerd.io/v1alpha1", "meshtlsauthentications", "MeshTLSAuthentication", true},
+	{"cert-manager.io/v1", "issuers", "Issuer", true},
+	{"cert-manager.io/v1", "certificates", "Certificate", true},
+}
+
+// fakeCluster is an in-memory Kubernetes API server storing the objects as JSON
+// documents. It serves discovery, get, list with label selectors, create, update,
+// merge patches and delete, which covers the clients the adapter uses. Requests
+// can be intercepted with hooks, the mutating requests it received are recorded
+// and group versions can be disabled to emulate clusters which do not serve them
+type fakeCluster struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	objects  map[string]map[string]interface{}
+	hooks    map[string]http.HandlerFunc
+	disabled map[string]bool
+	requests []string
+	version  int
+}
+
+// newFakeLinkerd returns a handler whose clients talk to a new fake cluster
+func newFakeLinkerd(t *testing.T) (*Linkerd, *fakeCluster) {
+	cluster := &fakeCluster{objects: map[string]map[string]interface{}{}, hooks: map[string]http.HandlerFunc{}, disabled: map[string]bool{}}
+	cluster.Server = httptest.NewServer(http.HandlerFunc(cluster.serve))
+	t.Cleanup(cluster.Close)
+
+	cfg := rest.Config{Host: cluster.URL}
+	kubeClient, err := kubernetes.NewForConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := logger.New("test", logger.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Progress events are discarded
+	events := make(chan interface{})
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-events:
+			case <-done:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(done) })
+
+	linkerd := &Linkerd{
+		Adapter: adapter.Adapter{
+			Log:               log,
+			Channel:           &events,
+			KubeClient:        kubeClient,
+			DynamicKubeClient: dynamicClient,
+			RestConfig:        cfg,
+			MesheryKubeclient: &mesherykube.Client{RestConfig: cfg, KubeClient: kubeClient, DynamicKubeClient: dynamicClient},
+		},
+		clusters: newClusterSet(),
+		faults:   newFaultTimers(),
+	}
+	return linkerd, cluster
+}
+
+// hook serves the requests with the method to the path with h instead of the store
+func (c *fakeCluster) hook(method, path string, h http.HandlerFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks[method+" "+path] = h
+}
+
+// disable stops serving the group version, e.g. cert-manager.io/v1
+func (c *fakeCluster) disable(groupVersion string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled[groupVersion] = true
+}
+
+// mutations returns the mutating requests received so far as "METHOD path"
+func (c *fakeCluster) mutations() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.requests...)
+}
+
+// object returns the stored object, nil if there is none
+func (c *fakeCluster) object(groupVersion, resource, namespace, name string) map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.objects[objectKey(groupVersion, resource, namespace, name)]
+}
+
+func objectKey(groupVersion, resource, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", groupVersion, resource, namespace, name)
+}
+
+func (c *fakeCluster) serve(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	h, ok := c.hooks[r.Method+" "+r.URL.Path]
+	if r.Method != http.MethodGet {
+		c.requests = append(c.requests, r.Method+" "+r.URL.Path)
+	}
+	c.mu.Unlock()
+	if ok {
+		h(w, r)
+		return
+	}
+
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var groupVersion string
+	switch {
+	case len(segments) >= 1 && segments[0] == "api" && len(segments) == 1:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"kind": "APIVersions", "versions": []string{"v1"}})
+		return
+	case len(segments) == 1 && segments[0] == "apis":
+		c.mu.Lock()
+		groups := discoveryGroups(c.disabled)
+		c.mu.Unlock()
+		writeJSON(w, http.StatusOK, groups)
+		return
+	case segments[0] == "api" && len(segments) >= 2:
+		groupVersion, segments = segments[1], segments[2:]
+	case segments[0] == "apis" && len(segments) >= 3:
+		groupVersion, segments = segments[1]+"/"+segments[2], segments[3:]
+	default:
+		writeStatus(w, http.StatusNotFound, "NotFound", r.URL.Path)
+		return
+	}
+	c.mu.Lock()
+	disabled := c.disabled[groupVersion]
+	c.mu.Unlock()
+	if disabled {
+		writeStatus(w, http.StatusNotFound, "NotFound", r.URL.Path)
+		return
+	}
+	if len(segments) == 0 {
+		if r.Method == http.MethodGet {
+			writeJSON(w, http.StatusOK, discoveryResources(groupVersion))
+			return
+		}
+		writeStatus(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.URL.Path)
+		return
+	}
+
+	namespace := ""
+	if len(segments) >= 3 && segments[0] == "namespaces" {
+		namespace, segments = segments[1], segments[2:]
+	}
+	var res *fakeResource
+	for i := range fakeResources {
+		if fakeResources[i].groupVersion == groupVersion && fakeResources[i].resource == segments[0] {
+			res = &fakeResources[i]
+		}
+	}
+	if res == nil || len(segments) > 2 {
+		writeStatus(w, http.StatusNotFound, "NotFound", r.URL.Path)
+		return
+	}
+	name := ""
+	if len(segments) == 2 {
+		name = segments[1]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		c.list(w, r, res, namespace)
+	case r.Method == http.MethodGet:
+		obj, ok := c.objects[objectKey(res.groupVersion, res.resource, namespace, name)]
+		if !ok {
+			writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", res.resource, name))
+			return
+		}
+		writeJSON(w, http.StatusOK, obj)
+	case r.Method == http.MethodPost:
+		c.store(w, r, res, namespace, "", true)
+	case r.Method == http.MethodPut:
+		c.store(w, r, res, namespace, name, false)
+	case r.Method == http.MethodPatch:
+		c.patch(w, r, res, namespace, name)
+	case r.Method == http.MethodDelete:
+		key := objectKey(res.groupVersion, res.resource, namespace, name)
+		if _, ok := c.objects[key]; !ok {
+			writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", res.resource, name))
+			return
+		}
+		delete(c.objects, key)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"kind": "Status", "apiVersion": "v1", "status": "Success"})
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.URL.Path)
+	}
+}
+
+func (c *fakeCluster) list(w http.ResponseWriter, r *http.Request, res *fakeResource, namespace string) {
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	prefix := objectKey(res.groupVersion, res.resource, namespace, "")
+	if namespace == "" {
+		prefix = fmt.Sprintf("%s/%s/", res.groupVersion, res.resource)
+	}
+	keys := []string{}
+	for key := range c.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	items := []interface{}{}
+	for _, key := range keys {
+		obj := c.objects[key]
+		set := labels.Set{}
+		if meta, ok := obj["metadata"].(map[string]interface{}); ok {
+			if l, ok := meta["labels"].(map[string]interface{}); ok {
+				for k, v := range l {
+					set[k] = fmt.Sprint(v)
+				}
+			}
+		}
+		if selector.Matches(set) {
+			items = append(items, obj)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"apiVersion": res.groupVersion,
+		"kind":       res.kind + "List",
+		"metadata":   map[string]interface{}{"resourceVersion": fmt.Sprint(c.version)},
+		"items":      items,
+	})
+}
+
+// store creates the object of the request, or replaces the named one
+func (c *fakeCluster) store(w http.ResponseWriter, r *http.Request, res *fakeResource, namespace, name string, create bool) {
+	obj := map[string]interface{}{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, &obj)
+	}
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	meta, _ := obj["metadata"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+		obj["metadata"] = meta
+	}
+	if create {
+		name, _ = meta["name"].(string)
+	}
+	if namespace != "" {
+		meta["namespace"] = namespace
+	}
+
+	key := objectKey(res.groupVersion, res.resource, namespace, name)
+	_, exists := c.objects[key]
+	switch {
+	case create && exists:
+		writeStatus(w, http.StatusConflict, "AlreadyExists", fmt.Sprintf("%s %q already exists", res.resource, name))
+		return
+	case !create && !exists:
+		writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", res.resource, name))
+		return
+	}
+
+	c.version++
+	meta["resourceVersion"] = fmt.Sprint(c.version)
+	obj["apiVersion"] = res.groupVersion
+	obj["kind"] = res.kind
+	c.objects[key] = obj
+
+	code := http.StatusOK
+	if create {
+		code = http.StatusCreated
+	}
+	writeJSON(w, code, obj)
+}
+
+// patch applies a merge patch, strategic merge patches are treated as merge patches
+func (c *fakeCluster) patch(w http.ResponseWriter, r *http.Request, res *fakeResource, namespace, name string) {
+	key := objectKey(res.groupVersion, res.resource, namespace, name)
+	obj, ok := c.objects[key]
+	if !ok {
+		writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", res.resource, name))
+		return
+	}
+	patch := map[string]interface{}{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, &patch)
+	}
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+
+	mergePatch(obj, patch)
+	c.version++
+	obj["metadata"].(map[string]interface{})["resourceVersion"] = fmt.Sprint(c.version)
+	writeJSON(w, http.StatusOK, obj)
+}
+
+func mergePatch(obj, patch map[string]interface{}) {
+	for k, v := range patch {
+		if v == nil {
+			delete(obj, k)
+			continue
+		}
+		if p, ok := v.(map[string]interface{}); ok {
+			if o, ok := obj[k].(map[string]interface{}); ok {
+				mergePatch(o, p)
+				continue
+			}
+			o := map[string]interface{}{}
+			mergePatch(o, p)
+			obj[k] = o
+			continue
+		}
+		obj[k] = v
+	}
+}
+
+func discoveryGroups(disabled map[string]bool) map[string]interface{} {
+	versions := map[string][]string{}
+	order := []string{}
+	seen := map[string]bool{}
+	for _, res := range fakeResources {
+		if seen[res.groupVersion] || disabled[res.groupVersion] {
+			continue
+		}
+		seen[res.groupVersion] = true
+		parts := strings.SplitN(res.groupVersion, "/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if _, ok := versions[parts[0]]; !ok {
+			order = append(order, parts[0])
+		}
+		versions[parts[0]] = append(versions[parts[0]], parts[1])
+	}
+
+	groups := []interface{}{}
+	for _, group := range order {
+		list := []interface{}{}
+		for _, v := range versions[group] {
+			list = append(list, map[string]interface{}{"groupVersion": group + "/" + v, "version": v})
+		}
+		groups = append(groups, map[string]interface{}{"name": group, "versions": list, "preferredVersion": list[0]})
+	}
+	return map[string]interface{}{"kind": "APIGroupList", "apiVersion": "v1", "groups": groups}
+}
+
+func discoveryResources(groupVersion string) map[string]interface{} {
+	resources := []interface{}{}
+	for _, res := range fakeResources {
+		if res.groupVersion == groupVersion {
+			resources = append(resources, map[string]interface{}{
+				"name":       res.resource,
+				"kind":       res.kind,
+				"namespaced": res.namespaced,
+				"verbs":      []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+			})
+		}
+	}
+	return map[string]interface{}{"kind": "APIResourceList", "apiVersion": "v1", "groupVersion": groupVersion, "resources": resources}
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason, message string) {
+	writeJSON(w, code, map[string]interface{}{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"status":     "Failure",
+		"reason":     reason,
+		"message":    message,
+		"code":       code,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
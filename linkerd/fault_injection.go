@@ -0,0 +1,332 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	faultSuffix            = "-fault"
+	faultLabel             = "meshery.io/fault-injection"
+	faultExpiresAnnotation = "meshery.io/fault-expires"
+	faultPercentAnnotation = "meshery.io/fault-percentage"
+	faultStatusAnnotation  = "meshery.io/fault-status-code"
+	defaultFaultImage      = "nginx:alpine"
+	defaultFaultStatusCode = 500
+	defaultFaultTTL        = 10 * time.Minute
+	faultBackendTemplate   = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+  labels:
+    %[3]s: %[4]s
+data:
+  default.conf: |
+    server {
+      listen 80;
+      location / {
+        return %[5]d;
+      }
+    }
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+  labels:
+    %[3]s: %[4]s
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: %[1]s
+  template:
+    metadata:
+      annotations:
+        linkerd.io/inject: enabled
+      labels:
+        app: %[1]s
+    spec:
+      containers:
+      - name: fault
+        image: %[6]s
+        ports:
+        - containerPort: 80
+        volumeMounts:
+        - name: config
+          mountPath: /etc/nginx/conf.d
+      volumes:
+      - name: config
+        configMap:
+          name: %[1]s
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+  labels:
+    %[3]s: %[4]s
+spec:
+  selector:
+    app: %[1]s
+  ports:
+%[7]s---
+apiVersion: split.smi-spec.io/v1alpha1
+kind: TrafficSplit
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+  labels:
+    %[3]s: %[4]s
+  annotations:
+    %[8]s: "%[9]s"
+    %[10]s: "%[11]d"
+    %[12]s: "%[5]d"
+spec:
+  service: %[4]s
+  backends:
+  - service: %[4]s
+    weight: %[13]d
+  - service: %[1]s
+    weight: %[11]d
+`
+)
+
+var trafficSplitResource = schema.GroupVersionResource{Group: "split.smi-spec.io", Version: "v1alpha1", Resource: "trafficsplits"}
+
+// FaultInjectionOptions is the body of the fault-injection operation, a delete
+// operation only needs the service
+type FaultInjectionOptions struct {
+	Service string `json:"service"`
+	// Percentage of the requests to the service answered by the fault backend
+	Percentage int `json:"percentage,omitempty"`
+	// StatusCode the fault backend responds with, defaults to 500
+	StatusCode int `json:"statusCode,omitempty"`
+	// Image of the fault backend, an nginx image, defaults to nginx:alpine
+	Image string `json:"image,omitempty"`
+	// TTL after which the fault is removed, defaults to ten minutes
+	TTL string `json:"ttl,omitempty"`
+}
+
+// ActiveFault is a fault injected into the traffic of a service
+type ActiveFault struct {
+	Service    string `json:"service"`
+	Namespace  string `json:"namespace"`
+	Percentage int    `json:"percentage"`
+	StatusCode int    `json:"statusCode"`
+	Expires    string `json:"expires"`
+}
+
+// faultTimers remove the injected faults once their TTL elapsed, they are
+// keyed by the kubeconfig context, the namespace and the service of the fault
+type faultTimers struct {
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	// resumed are the contexts whose faults have been rescheduled after a restart
+	resumed map[string]bool
+}
+
+func newFaultTimers() *faultTimers {
+	return &faultTimers{timers: map[string]*time.Timer{}, resumed: map[string]bool{}}
+}
+
+// faultKey identifies the fault of the service in the cluster of the handler
+func (linkerd *Linkerd) faultKey(namespace, service string) string {
+	return fmt.Sprintf("%s/%s/%s", linkerd.currentContext(), namespace, service)
+}
+
+// injectFault deploys a backend answering every request with the status code and splits
+// the given percentage of the traffic of the service to it until the TTL elapsed
+func (linkerd *Linkerd) injectFault(namespace, body string, del bool) (string, string, error) {
+	st := status.Deploying
+	if del {
+		st = status.Removing
+	}
+
+	opts := FaultInjectionOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return st, "", err
+	}
+	if opts.Service == "" {
+		return st, "", ErrFaultInjection(fmt.Errorf("no service given"))
+	}
+	if del {
+		if err := linkerd.removeFault(namespace, opts.Service); err != nil {
+			return st, "", err
+		}
+		return status.Removed, "", nil
+	}
+
+	if opts.Percentage <= 0 || opts.Percentage > 100 {
+		return st, "", ErrFaultInjection(fmt.Errorf("percentage has to be between 1 and 100"))
+	}
+	if opts.StatusCode == 0 {
+		opts.StatusCode = defaultFaultStatusCode
+	}
+	if opts.StatusCode < 100 || opts.StatusCode > 599 {
+		return st, "", ErrFaultInjection(fmt.Errorf("invalid status code %d", opts.StatusCode))
+	}
+	if opts.Image == "" {
+		opts.Image = defaultFaultImage
+	}
+	ttl := defaultFaultTTL
+	if opts.TTL != "" {
+		d, err := time.ParseDuration(opts.TTL)
+		if err != nil {
+			return st, "", ErrParseOperationBody(err)
+		}
+		ttl = d
+	}
+
+	// The fault backend has to serve the ports of the service for the split to route to it
+	svc, err := linkerd.KubeClient.CoreV1().Services(namespace).Get(context.TODO(), opts.Service, metav1.GetOptions{})
+	if err != nil {
+		return st, "", ErrFaultInjection(err)
+	}
+	if len(svc.Spec.Ports) == 0 {
+		return st, "", ErrFaultInjection(fmt.Errorf("service %s exposes no ports", opts.Service))
+	}
+	var ports strings.Builder
+	for _, p := range svc.Spec.Ports {
+		fmt.Fprintf(&ports, "  - name: %q\n    port: %d\n    targetPort: 80\n", p.Name, p.Port)
+	}
+
+	expires := time.Now().Add(ttl).UTC()
+	manifest := fmt.Sprintf(faultBackendTemplate,
+		opts.Service+faultSuffix, namespace, faultLabel, opts.Service, opts.StatusCode, opts.Image, ports.String(),
+		faultExpiresAnnotation, expires.Format(time.RFC3339), faultPercentAnnotation, opts.Percentage, faultStatusAnnotation,
+		100-opts.Percentage)
+	if err = linkerd.applyManifest([]byte(manifest), false, namespace); err != nil {
+		return st, "", ErrFaultInjection(err)
+	}
+	linkerd.scheduleFaultRemoval(namespace, opts.Service, expires)
+
+	return status.Deployed, fmt.Sprintf("%d%% of the requests to %s are answered with %d until %s", opts.Percentage, opts.Service, opts.StatusCode, expires.Format(time.RFC3339)), nil
+}
+
+// removeFault deletes the traffic split and the fault backend of the service
+func (linkerd *Linkerd) removeFault(namespace, service string) error {
+	key := linkerd.faultKey(namespace, service)
+	linkerd.faults.mu.Lock()
+	if t, ok := linkerd.faults.timers[key]; ok {
+		t.Stop()
+		delete(linkerd.faults.timers, key)
+	}
+	linkerd.faults.mu.Unlock()
+
+	name := service + faultSuffix
+	deletes := []func() error{
+		func() error {
+			return linkerd.DynamicKubeClient.Resource(trafficSplitResource).Namespace(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		},
+		func() error {
+			return linkerd.KubeClient.AppsV1().Deployments(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		},
+		func() error {
+			return linkerd.KubeClient.CoreV1().Services(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		},
+		func() error {
+			return linkerd.KubeClient.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		},
+	}
+	for _, d := range deletes {
+		if err := d(); err != nil && !kubeerror.IsNotFound(err) {
+			return ErrFaultInjection(err)
+		}
+	}
+	return nil
+}
+
+// scheduleFaultRemoval removes the fault of the service once it expires,
+// replacing an earlier schedule of the same fault
+func (linkerd *Linkerd) scheduleFaultRemoval(namespace, service string, expires time.Time) {
+	key := linkerd.faultKey(namespace, service)
+	linkerd.faults.mu.Lock()
+	defer linkerd.faults.mu.Unlock()
+
+	if t, ok := linkerd.faults.timers[key]; ok {
+		t.Stop()
+	}
+	linkerd.faults.timers[key] = time.AfterFunc(time.Until(expires), func() {
+		if err := linkerd.removeFault(namespace, service); err != nil {
+			linkerd.Log.Error(err)
+			return
+		}
+		linkerd.Log.Info(fmt.Sprintf("Fault injected into %s/%s expired and was removed", namespace, service))
+	})
+}
+
+// resumeFaultRemovalsOnce resumes the removal of the faults of the cluster of the
+// handler in the background, once per kubeconfig context
+func (linkerd *Linkerd) resumeFaultRemovalsOnce() {
+	name := linkerd.currentContext()
+	linkerd.faults.mu.Lock()
+	resumed := linkerd.faults.resumed[name]
+	linkerd.faults.resumed[name] = true
+	linkerd.faults.mu.Unlock()
+
+	if !resumed {
+		go linkerd.resumeFaultRemovals()
+	}
+}
+
+// resumeFaultRemovals schedules the removal of the faults injected before the adapter restarted
+func (linkerd *Linkerd) resumeFaultRemovals() {
+	faults, err := linkerd.activeFaults()
+	if err != nil {
+		linkerd.Log.Warn(err)
+		return
+	}
+	for _, f := range faults {
+		expires, err := time.Parse(time.RFC3339, f.Expires)
+		if err != nil {
+			expires = time.Now()
+		}
+		linkerd.scheduleFaultRemoval(f.Namespace, f.Service, expires)
+	}
+}
+
+// activeFaults lists the faults injected into the cluster, there are none if the SMI CRDs are missing
+func (linkerd *Linkerd) activeFaults() ([]ActiveFault, error) {
+	splits, err := linkerd.DynamicKubeClient.Resource(trafficSplitResource).List(context.TODO(), metav1.ListOptions{LabelSelector: faultLabel})
+	if kubeerror.IsNotFound(err) {
+		return []ActiveFault{}, nil
+	}
+	if err != nil {
+		return nil, ErrFaultInjection(err)
+	}
+
+	faults := []ActiveFault{}
+	for _, split := range splits.Items {
+		annotations := split.GetAnnotations()
+		percentage, _ := strconv.Atoi(annotations[faultPercentAnnotation])
+		statusCode, _ := strconv.Atoi(annotations[faultStatusAnnotation])
+		faults = append(faults, ActiveFault{
+			Service:    split.GetLabels()[faultLabel],
+			Namespace:  split.GetNamespace(),
+			Percentage: percentage,
+			StatusCode: statusCode,
+			Expires:    annotations[faultExpiresAnnotation],
+		})
+	}
+	sort.Slice(faults, func(i, j int) bool {
+		if faults[i].Namespace != faults[j].Namespace {
+			return faults[i].Namespace < faults[j].Namespace
+		}
+		return faults[i].Service < faults[j].Service
+	})
+	return faults, nil
+}
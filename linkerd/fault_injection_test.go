@@ -0,0 +1,112 @@
+package linkerd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// hasFaultTimer returns true if the removal of the fault of the service is scheduled
+func hasFaultTimer(linkerd *Linkerd, namespace, service string) bool {
+	linkerd.faults.mu.Lock()
+	defer linkerd.faults.mu.Unlock()
+	_, ok := linkerd.faults.timers[linkerd.faultKey(namespace, service)]
+	return ok
+}
+
+func TestInjectFault(t *testing.T) {
+	linkerd, cluster := newFakeLinkerd(t)
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "books"},
+		Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 7002}}},
+	}
+	if _, err := linkerd.KubeClient.CoreV1().Services("bookapp").Create(context.TODO(), svc, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := linkerd.injectFault("bookapp", `{"service": "books", "percentage": 20, "ttl": "1h"}`, false); err != nil {
+		t.Fatal(err)
+	}
+	faults, err := linkerd.activeFaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(faults) != 1 || faults[0].Service != "books" || faults[0].Percentage != 20 || faults[0].StatusCode != defaultFaultStatusCode {
+		t.Fatalf("got faults %+v", faults)
+	}
+	expires, err := time.Parse(time.RFC3339, faults[0].Expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Until(expires); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("fault expires in %s, want 1h", d)
+	}
+	if !hasFaultTimer(linkerd, "bookapp", "books") {
+		t.Error("removal of the fault is not scheduled")
+	}
+
+	if _, _, err = linkerd.injectFault("bookapp", `{"service": "books"}`, true); err != nil {
+		t.Fatal(err)
+	}
+	if hasFaultTimer(linkerd, "bookapp", "books") {
+		t.Error("removal of the fault is still scheduled")
+	}
+	for _, resource := range []string{"configmaps", "services"} {
+		if cluster.object("v1", resource, "bookapp", "books"+faultSuffix) != nil {
+			t.Errorf("%s of the fault backend was not removed", resource)
+		}
+	}
+	if cluster.object("apps/v1", "deployments", "bookapp", "books"+faultSuffix) != nil {
+		t.Error("deployment of the fault backend was not removed")
+	}
+	if cluster.object("split.smi-spec.io/v1alpha1", "trafficsplits", "bookapp", "books"+faultSuffix) != nil {
+		t.Error("traffic split was not removed")
+	}
+}
+
+func TestResumeFaultRemovals(t *testing.T) {
+	linkerd, cluster := newFakeLinkerd(t)
+	for service, expires := range map[string]time.Time{
+		"books":   time.Now().Add(-time.Minute),
+		"authors": time.Now().Add(time.Hour),
+	} {
+		split := &unstructured.Unstructured{}
+		split.SetAPIVersion("split.smi-spec.io/v1alpha1")
+		split.SetKind("TrafficSplit")
+		split.SetName(service + faultSuffix)
+		split.SetLabels(map[string]string{faultLabel: service})
+		split.SetAnnotations(map[string]string{faultExpiresAnnotation: expires.UTC().Format(time.RFC3339)})
+		if _, err := linkerd.DynamicKubeClient.Resource(trafficSplitResource).Namespace("bookapp").Create(context.TODO(), split, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	linkerd.resumeFaultRemovals()
+	err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		return cluster.object("split.smi-spec.io/v1alpha1", "trafficsplits", "bookapp", "books"+faultSuffix) == nil, nil
+	})
+	if err != nil {
+		t.Error("expired fault was not removed")
+	}
+	if hasFaultTimer(linkerd, "bookapp", "books") {
+		t.Error("removal of the expired fault is still scheduled")
+	}
+
+	if cluster.object("split.smi-spec.io/v1alpha1", "trafficsplits", "bookapp", "authors"+faultSuffix) == nil {
+		t.Error("fault removed before it expired")
+	}
+	if !hasFaultTimer(linkerd, "bookapp", "authors") {
+		t.Error("removal of the fault is not scheduled")
+	}
+	if err = linkerd.removeFault("bookapp", "authors"); err != nil {
+		t.Fatal(err)
+	}
+	if hasFaultTimer(linkerd, "bookapp", "authors") {
+		t.Error("timer of the removed fault was not stopped")
+	}
+}
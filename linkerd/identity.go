@@ -0,0 +1,427 @@
+package linkerd
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultControlPlaneNamespace = "linkerd"
+	defaultTrustDomain           = "cluster.local"
+
+	identityIssuerSecret        = "linkerd-identity-issuer"
+	identityTrustRootsConfigMap = "linkerd-identity-trust-roots"
+	linkerdConfigMap            = "linkerd-config"
+	identityDeployment          = "linkerd-identity"
+
+	// certificateNameAnnotation is set by cert-manager on the secrets of its certificates
+	certificateNameAnnotation = "cert-manager.io/certificate-name"
+
+	// proxyInboundPort is the port on which the linkerd proxy
+	// terminates mTLS for the meshed workload
+	proxyInboundPort = 4143
+
+	defaultIssuerValidity = 365 * 24 * time.Hour
+)
+
+// linkerdConfigValues are the fields of the "values" key of the
+// linkerd-config configmap which are relevant to identity management.
+// Linkerd 2.9 keeps them under "global" while 2.10+ has them at the top level
+type linkerdConfigValues struct {
+	IdentityTrustAnchorsPEM string `json:"identityTrustAnchorsPEM,omitempty"`
+	IdentityTrustDomain     string `json:"identityTrustDomain,omitempty"`
+	Global                  *struct {
+		IdentityTrustAnchorsPEM string `json:"identityTrustAnchorsPEM,omitempty"`
+		IdentityTrustDomain     string `json:"identityTrustDomain,omitempty"`
+	} `json:"global,omitempty"`
+}
+
+// RotateIssuerOptions is the body of the rotate-issuer operation
+type RotateIssuerOptions struct {
+	// TrustAnchorCert and TrustAnchorKey are the PEM encoded certificate
+	// and private key of the trust anchor currently used by the control plane.
+	// Linkerd does not store the trust anchor key in the cluster hence it
+	// must be supplied by the user
+	TrustAnchorCert string `json:"trustAnchorCert"`
+	TrustAnchorKey  string `json:"trustAnchorKey"`
+
+	// Validity of the new issuer certificate, defaults to one year
+	Validity string `json:"validity,omitempty"`
+}
+
+// rotateIssuer generates a new identity issuer signed by the existing trust anchor,
+// stores it in the linkerd-identity-issuer secret, restarts the identity component
+// and verifies that newly issued proxy certificates chain to the trust anchor. An
+// issuer managed by cert-manager is refused, cert-manager would overwrite it
+func (linkerd *Linkerd) rotateIssuer(opID, namespace, body string) (string, error) {
+	st := status.Patching
+	if namespace == "" {
+		namespace = defaultControlPlaneNamespace
+	}
+
+	opts := RotateIssuerOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return st, err
+	}
+	validity := defaultIssuerValidity
+	if opts.Validity != "" {
+		d, err := time.ParseDuration(opts.Validity)
+		if err != nil {
+			return st, ErrParseOperationBody(err)
+		}
+		validity = d
+	}
+
+	if err := linkerd.checkIssuerUnmanaged(namespace); err != nil {
+		return st, err
+	}
+
+	anchors, err := linkerd.getTrustAnchors(namespace)
+	if err != nil {
+		return st, ErrIdentityCertificate(err)
+	}
+	anchor, anchorKey, err := matchTrustAnchor(anchors, []byte(opts.TrustAnchorCert), []byte(opts.TrustAnchorKey))
+	if err != nil {
+		return st, ErrIdentityCertificate(err)
+	}
+	linkerd.streamProgress(opID, "Trust anchor verified", fmt.Sprintf("Trust anchor %s matches the control plane in namespace %s", anchor.Subject.CommonName, namespace))
+
+	issuer, err := linkerd.replaceIssuer(opID, namespace, anchor, anchorKey, validity)
+	if err != nil {
+		return st, err
+	}
+
+	if err = linkerd.verifyIssuedCertificate(opID, namespace, issuer, anchors); err != nil {
+		return st, err
+	}
+
+	return status.Patched, nil
+}
+
+// replaceIssuer generates a new issuer signed by the given trust anchor, stores it in the
+// linkerd-identity-issuer secret and restarts the identity component to pick it up
+func (linkerd *Linkerd) replaceIssuer(opID, namespace string, anchor *x509.Certificate, anchorKey crypto.Signer, validity time.Duration) ([]*x509.Certificate, error) {
+	secret, err := linkerd.KubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), identityIssuerSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, ErrIdentityCertificate(err)
+	}
+	certKey, keyKey := issuerSecretKeys(secret)
+	current, err := parseCertificates(secret.Data[certKey])
+	if err != nil {
+		return nil, ErrIdentityCertificate(err)
+	}
+
+	certPEM, keyPEM, err := newIssuerCertificate(anchor, anchorKey, current[0].Subject, validity)
+	if err != nil {
+		return nil, ErrIdentityCertificate(err)
+	}
+	issuer, _ := parseCertificates(certPEM)
+	linkerd.streamProgress(opID, "Issuer certificate generated", fmt.Sprintf("New issuer %s is valid until %s", issuer[0].Subject.CommonName, issuer[0].NotAfter.Format(time.RFC3339)))
+
+	secret.Data[certKey] = certPEM
+	secret.Data[keyKey] = keyPEM
+	if secret.Type == corev1.SecretTypeTLS {
+		secret.Data["ca.crt"] = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: anchor.Raw})
+	}
+	_, err = linkerd.KubeClient.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, ErrIdentityCertificate(err)
+	}
+	linkerd.streamProgress(opID, "Issuer secret updated", fmt.Sprintf("Secret %s/%s now holds the new issuer", namespace, identityIssuerSecret))
+
+	identity := workload{Kind: deploymentKind, Namespace: namespace, Name: identityDeployment}
+	if err = linkerd.restartWorkload(identity); err != nil {
+		return nil, err
+	}
+	if err = linkerd.waitForWorkload(identity, defaultRolloutTimeout); err != nil {
+		return nil, err
+	}
+	linkerd.streamProgress(opID, "Identity component restarted", fmt.Sprintf("Deployment %s/%s is ready", namespace, identityDeployment))
+
+	return issuer, nil
+}
+
+// verifyIssuedCertificate checks that the proxy of the restarted identity component
+// presents a certificate issued by the given issuer which chains to one of the roots
+func (linkerd *Linkerd) verifyIssuedCertificate(opID, namespace string, issuer, roots []*x509.Certificate) error {
+	leaf, err := linkerd.getProxyCertificate(namespace, metav1.ListOptions{LabelSelector: "linkerd.io/control-plane-component=identity"})
+	if err != nil {
+		return ErrIdentityCertificate(err)
+	}
+	if err = verifyChain(leaf, issuer, roots); err != nil {
+		return ErrIdentityCertificate(err)
+	}
+	linkerd.streamProgress(opID, "Proxy certificate verified", fmt.Sprintf("Certificate of %s chains to the trust anchor through the new issuer", leaf.Subject.CommonName))
+
+	return nil
+}
+
+// checkIssuerUnmanaged refuses to replace an issuer secret managed by cert-manager, which would overwrite it
+func (linkerd *Linkerd) checkIssuerUnmanaged(namespace string) error {
+	secret, err := linkerd.KubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), identityIssuerSecret, metav1.GetOptions{})
+	if err != nil {
+		return ErrIdentityCertificate(err)
+	}
+	if certificate, ok := secret.Annotations[certificateNameAnnotation]; ok {
+		return ErrIdentityCertificate(fmt.Errorf("the issuer is managed by the cert-manager certificate %s/%s, renew the certificate with cert-manager instead", namespace, certificate))
+	}
+	return nil
+}
+
+// getLinkerdConfigValues returns the identity related values of the linkerd-config configmap
+func (linkerd *Linkerd) getLinkerdConfigValues(namespace string) (*linkerdConfigValues, error) {
+	cm, err := linkerd.KubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), linkerdConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	values := &linkerdConfigValues{}
+	if err = yaml.Unmarshal([]byte(cm.Data["values"]), values); err != nil {
+		return nil, err
+	}
+	if values.Global != nil {
+		if values.IdentityTrustAnchorsPEM == "" {
+			values.IdentityTrustAnchorsPEM = values.Global.IdentityTrustAnchorsPEM
+		}
+		if values.IdentityTrustDomain == "" {
+			values.IdentityTrustDomain = values.Global.IdentityTrustDomain
+		}
+	}
+	if values.IdentityTrustDomain == "" {
+		values.IdentityTrustDomain = defaultTrustDomain
+	}
+
+	return values, nil
+}
+
+// getTrustAnchors returns the trust anchors of the control plane. Linkerd 2.11+
+// keeps them in the linkerd-identity-trust-roots configmap, earlier versions in linkerd-config
+func (linkerd *Linkerd) getTrustAnchors(namespace string) ([]*x509.Certificate, error) {
+	cm, err := linkerd.KubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), identityTrustRootsConfigMap, metav1.GetOptions{})
+	if err == nil {
+		return parseCertificates([]byte(cm.Data["ca-bundle.crt"]))
+	}
+	if !kubeerror.IsNotFound(err) {
+		return nil, err
+	}
+
+	values, err := linkerd.getLinkerdConfigValues(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCertificates([]byte(values.IdentityTrustAnchorsPEM))
+}
+
+// getProxyCertificate returns the leaf certificate presented by the proxy of the
+// first running pod matched by the list options
+func (linkerd *Linkerd) getProxyCertificate(namespace string, listOpts metav1.ListOptions) (*x509.Certificate, error) {
+	pods, err := linkerd.KubeClient.CoreV1().Pods(namespace).List(context.TODO(), listOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := linkerd.getLinkerdConfigValues(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		fw, err := linkerd.portForward(pod.Namespace, pod.Name, proxyInboundPort)
+		if err != nil {
+			return nil, err
+		}
+		defer fw.Close()
+
+		// The proxy only terminates TLS for its own identity hence the server
+		// name has to be set, the presented chain is verified separately
+		serverName := proxyIdentity(&pod, namespace, values.IdentityTrustDomain)
+		conn, err := tls.Dial("tcp", fw.Address(), &tls.Config{
+			ServerName: serverName,
+			// #nosec
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		certs := conn.ConnectionState().PeerCertificates
+		if len(certs) == 0 {
+			return nil, fmt.Errorf("proxy of pod %s/%s presented no certificate", pod.Namespace, pod.Name)
+		}
+		return certs[0], nil
+	}
+
+	return nil, fmt.Errorf("no running pod found with selector %q", listOpts.LabelSelector)
+}
+
+// proxyIdentity is the identity the proxy of the pod is issued by the control plane
+func proxyIdentity(pod *corev1.Pod, controlPlaneNamespace, trustDomain string) string {
+	return fmt.Sprintf("%s.%s.serviceaccount.identity.%s.%s", pod.Spec.ServiceAccountName, pod.Namespace, controlPlaneNamespace, trustDomain)
+}
+
+// issuerSecretKeys returns the data keys of the issuer certificate and key,
+// which depend on the identity scheme of the control plane
+func issuerSecretKeys(secret *corev1.Secret) (string, string) {
+	if secret.Type == corev1.SecretTypeTLS {
+		return corev1.TLSCertKey, corev1.TLSPrivateKeyKey
+	}
+	return "crt.pem", "key.pem"
+}
+
+// matchTrustAnchor parses the given certificate and key and makes sure that
+// the certificate is one of the trust anchors and the key belongs to it
+func matchTrustAnchor(anchors []*x509.Certificate, certPEM, keyPEM []byte) (*x509.Certificate, crypto.Signer, error) {
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !reflect.DeepEqual(certs[0].PublicKey, key.Public()) {
+		return nil, nil, fmt.Errorf("trust anchor key does not match the trust anchor certificate")
+	}
+
+	for _, anchor := range anchors {
+		if anchor.Equal(certs[0]) {
+			return anchor, key, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("certificate %s is not a trust anchor of the control plane", certs[0].Subject.CommonName)
+}
+
+// newIssuerCertificate creates an intermediate CA certificate with a new ECDSA P-256 key
+// signed by the parent certificate, the returned certificate and key are PEM encoded
+func newIssuerCertificate(parent *x509.Certificate, parentKey crypto.Signer, subject pkix.Name, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Allow for clock skew between the adapter and the cluster
+	notBefore := time.Now().Add(-5 * time.Minute)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            0,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
+
+// verifyChain verifies that the leaf certificate chains to one of the roots through the intermediates
+func verifyChain(leaf *x509.Certificate, intermediates, roots []*x509.Certificate) error {
+	opts := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, c := range roots {
+		opts.Roots.AddCert(c)
+	}
+	for _, c := range intermediates {
+		opts.Intermediates.AddCert(c)
+	}
+
+	_, err := leaf.Verify(opts)
+	return err
+}
+
+// parseCertificates decodes all the PEM encoded certificates in data
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+// parsePrivateKey decodes a PEM encoded EC, RSA or PKCS8 private key
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded private key found")
+	}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
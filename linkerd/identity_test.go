@@ -0,0 +1,173 @@
+package linkerd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newTestTrustAnchor returns a self-signed root certificate with its PEM encoded certificate and key
+func newTestTrustAnchor(t *testing.T, commonName string) (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestMatchTrustAnchor(t *testing.T) {
+	anchor, anchorPEM, anchorKey := newTestTrustAnchor(t, "root.linkerd.cluster.local")
+	other, otherPEM, otherKey := newTestTrustAnchor(t, "other.linkerd.cluster.local")
+
+	tests := []struct {
+		name    string
+		anchors []*x509.Certificate
+		cert    []byte
+		key     []byte
+		valid   bool
+	}{
+		{name: "trust anchor", anchors: []*x509.Certificate{other, anchor}, cert: anchorPEM, key: anchorKey, valid: true},
+		{name: "not a trust anchor", anchors: []*x509.Certificate{anchor}, cert: otherPEM, key: otherKey},
+		{name: "key of another certificate", anchors: []*x509.Certificate{anchor, other}, cert: anchorPEM, key: otherKey},
+		{name: "no key", anchors: []*x509.Certificate{anchor}, cert: anchorPEM},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, key, err := matchTrustAnchor(tt.anchors, tt.cert, tt.key)
+			if !tt.valid {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !cert.Equal(anchor) || key == nil {
+				t.Errorf("got certificate %s", cert.Subject.CommonName)
+			}
+		})
+	}
+}
+
+func TestNewIssuerCertificate(t *testing.T) {
+	anchor, anchorPEM, anchorKeyPEM := newTestTrustAnchor(t, "root.linkerd.cluster.local")
+	other, _, _ := newTestTrustAnchor(t, "other.linkerd.cluster.local")
+	anchorKey, err := parsePrivateKey(anchorKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subject := pkix.Name{CommonName: "identity.linkerd.cluster.local"}
+	certPEM, keyPEM, err := newIssuerCertificate(anchor, anchorKey, subject, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := parseCertificates(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issuer[0].Subject.CommonName != subject.CommonName || !issuer[0].IsCA {
+		t.Errorf("got issuer %s, CA %t", issuer[0].Subject.CommonName, issuer[0].IsCA)
+	}
+	if validity := issuer[0].NotAfter.Sub(issuer[0].NotBefore); validity != 48*time.Hour {
+		t.Errorf("got validity %s", validity)
+	}
+	if _, err = parsePrivateKey(keyPEM); err != nil {
+		t.Error(err)
+	}
+
+	if err = verifyChain(issuer[0], nil, []*x509.Certificate{anchor}); err != nil {
+		t.Errorf("issuer does not chain to its trust anchor: %s", err)
+	}
+	if err = verifyChain(issuer[0], nil, []*x509.Certificate{other}); err == nil {
+		t.Error("issuer chains to another trust anchor")
+	}
+
+	bundle, err := parseCertificates(append(anchorPEM, certPEM...))
+	if err != nil || len(bundle) != 2 {
+		t.Errorf("got %d certificates from the bundle: %v", len(bundle), err)
+	}
+}
+
+func TestIssuerSecretKeys(t *testing.T) {
+	tests := []struct {
+		secretType corev1.SecretType
+		cert, key  string
+	}{
+		{secretType: corev1.SecretTypeTLS, cert: "tls.crt", key: "tls.key"},
+		{secretType: corev1.SecretTypeOpaque, cert: "crt.pem", key: "key.pem"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.secretType), func(t *testing.T) {
+			cert, key := issuerSecretKeys(&corev1.Secret{Type: tt.secretType})
+			if cert != tt.cert || key != tt.key {
+				t.Errorf("got keys %s and %s", cert, key)
+			}
+		})
+	}
+}
+
+func TestCheckIssuerUnmanaged(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		unmanaged   bool
+	}{
+		{name: "unmanaged", unmanaged: true},
+		{name: "managed by cert-manager", annotations: map[string]string{certificateNameAnnotation: identityIssuerSecret}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkerd, _ := newFakeLinkerd(t)
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: identityIssuerSecret, Annotations: tt.annotations}}
+			if _, err := linkerd.KubeClient.CoreV1().Secrets("linkerd").Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := linkerd.checkIssuerUnmanaged("linkerd")
+			if tt.unmanaged && err != nil {
+				t.Error(err)
+			}
+			if !tt.unmanaged && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package linkerd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	injectAnnotation = "linkerd.io/inject"
+	injectEnabled    = "enabled"
+	injectDisabled   = "disabled"
+
+	// Labels the job controller adds to the pod template of a job
+	jobControllerUIDLabel = "controller-uid"
+	jobNameLabel          = "job-name"
+)
+
+// cronJobResources are the versions of the cronjobs API in order of preference,
+// batch/v1beta1 is gone since Kubernetes 1.25 and batch/v1 is new in 1.21
+var cronJobResources = []schema.GroupVersionResource{
+	{Group: "batch", Version: "v1", Resource: "cronjobs"},
+	{Group: "batch", Version: "v1beta1", Resource: "cronjobs"},
+}
+
+// InjectWorkloadsOptions is the body of the inject-workloads operation. Workloads are
+// selected by name, optionally prefixed with the kind as in "deployment/web", or by label
+// selector, all the workloads of the namespace are selected if neither is set
+type InjectWorkloadsOptions struct {
+	Workloads []string `json:"workloads,omitempty"`
+	Selector  string   `json:"selector,omitempty"`
+
+	// Timeout for the rollout of each workload, defaults to five minutes
+	Timeout string `json:"timeout,omitempty"`
+	// AllowConflicts injects workloads even if another mesh injects its sidecar into them
+	AllowConflicts bool `json:"allowConflicts,omitempty"`
+	// RerunJobs recreates the jobs named in Workloads with the annotation, which runs them
+	// again. Jobs which completed or have active pods are never recreated
+	RerunJobs bool `json:"rerunJobs,omitempty"`
+}
+
+// WorkloadInjectionStatus reports whether the pods of a workload got the linkerd proxy
+type WorkloadInjectionStatus struct {
+	Workload string `json:"workload"`
+	Inject   string `json:"inject"`
+	Pods     int    `json:"pods"`
+	// ProxyPods is the number of pods with a linkerd-proxy container
+	ProxyPods int `json:"proxyPods"`
+	// Ok is true if all the pods have the proxy, or none when injection is disabled
+	Ok      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// injectWorkloads enables proxy injection in the pod templates of the selected workloads,
+// or disables it for a delete operation, rolls them out and checks whether their new pods
+// have the linkerd proxy. The result of every workload is streamed as soon as it is known
+func (linkerd *Linkerd) injectWorkloads(opID, namespace, body string, del bool) ([]WorkloadInjectionStatus, error) {
+	opts := InjectWorkloadsOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	timeout := defaultRolloutTimeout
+	if opts.Timeout != "" {
+		d, err := time.ParseDuration(opts.Timeout)
+		if err != nil {
+			return nil, ErrParseOperationBody(err)
+		}
+		timeout = d
+	}
+
+	workloads, err := linkerd.selectWorkloads(namespace, opts.Workloads, opts.Selector)
+	if err != nil {
+		return nil, ErrInjectWorkloads(err)
+	}
+
+	value := injectEnabled
+	if del {
+		value = injectDisabled
+	}
+
+	report := make([]WorkloadInjectionStatus, 0, len(workloads))
+	for _, w := range workloads {
+		// Jobs are recreated to change their pod template, which reruns them, hence
+		// only those named in a request opting in to reruns are touched
+		result := linkerd.injectWorkload(w, value, timeout, opts.AllowConflicts, opts.RerunJobs && len(opts.Workloads) > 0)
+		report = append(report, result)
+
+		summary := fmt.Sprintf("%s: %d of %d pods have the linkerd proxy", w, result.ProxyPods, result.Pods)
+		if result.Message != "" {
+			summary = fmt.Sprintf("%s: %s", w, result.Message)
+		}
+		details, _ := json.Marshal(result)
+		linkerd.streamProgress(opID, summary, string(details))
+	}
+
+	return report, nil
+}
+
+// injectWorkload sets the inject annotation of the workload unless another mesh injects
+// it as well, waits for its rollout and reports its pods. Jobs are only recreated if rerun is set
+func (linkerd *Linkerd) injectWorkload(w workload, value string, timeout time.Duration, allowConflicts, rerun bool) WorkloadInjectionStatus {
+	result := WorkloadInjectionStatus{Workload: w.String(), Inject: value}
+
+	if w.Kind == jobKind && !rerun {
+		result.Message = "applies to new jobs only, name the job and set rerunJobs to run it again with the annotation"
+		return result
+	}
+
+	if err := linkerd.meshWorkload(w, value, allowConflicts); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	// The pods of cronjobs are only created with the next scheduled job
+	if w.Kind == cronJobKind {
+		result.Ok = true
+		result.Message = "pod template annotated, the next scheduled job is created with it"
+		return result
+	}
+
+	if err := linkerd.waitForWorkload(w, timeout); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	pods, err := linkerd.workloadPods(w)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Pods = len(pods)
+	for i := range pods {
+		if isMeshed(&pods[i]) {
+			result.ProxyPods++
+		}
+	}
+
+	if value == injectEnabled {
+		result.Ok = result.ProxyPods == result.Pods
+	} else {
+		result.Ok = result.ProxyPods == 0
+	}
+	if result.Pods == 0 {
+		result.Message = "workload has no pods, they get the linkerd proxy once it is scaled up"
+	} else if !result.Ok {
+		result.Message = fmt.Sprintf("%d of %d pods have the linkerd proxy, check that the proxy injector is running", result.ProxyPods, result.Pods)
+	}
+	return result
+}
+
+// meshWorkload sets the inject annotation of the workload. Enabling injection is
+// refused if another mesh injects the workload as well, unless allowConflicts is set
+func (linkerd *Linkerd) meshWorkload(w workload, value string, allowConflicts bool) error {
+	if value == injectEnabled && !allowConflicts {
+		if err := linkerd.checkWorkloadConflicts(w); err != nil {
+			return err
+		}
+	}
+	return linkerd.annotateWorkload(w, value)
+}
+
+// annotateWorkload sets the inject annotation on the pod template of the workload, which
+// is where the proxy injector looks for it. The template is stamped with the restart time
+// as well so that the workload is rolled out even if the annotation was already set
+func (linkerd *Linkerd) annotateWorkload(w workload, value string) error {
+	var err error
+	if w.Kind == jobKind {
+		err = linkerd.recreateJob(w, value)
+	} else {
+		err = linkerd.patchPodTemplateAnnotations(w, map[string]interface{}{
+			injectAnnotation:                    value,
+			"kubectl.kubernetes.io/restartedAt": time.Now().Format(time.RFC3339),
+		})
+	}
+	if err != nil {
+		return ErrInjectWorkloads(fmt.Errorf("%s: %s", w, err))
+	}
+
+	return nil
+}
+
+// patchPodTemplateAnnotations merges the annotations into the pod template of the workload,
+// annotations with a nil value are removed. Changing the template rolls the workload out
+func (linkerd *Linkerd) patchPodTemplateAnnotations(w workload, annotations map[string]interface{}) error {
+	spec := map[string]interface{}{
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": annotations,
+			},
+		},
+	}
+	if w.Kind == cronJobKind {
+		spec = map[string]interface{}{"jobTemplate": map[string]interface{}{"spec": spec}}
+	}
+	patch, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return err
+	}
+
+	apps := linkerd.KubeClient.AppsV1()
+	switch w.Kind {
+	case deploymentKind:
+		_, err = apps.Deployments(w.Namespace).Patch(context.TODO(), w.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case statefulSetKind:
+		_, err = apps.StatefulSets(w.Namespace).Patch(context.TODO(), w.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case daemonSetKind:
+		_, err = apps.DaemonSets(w.Namespace).Patch(context.TODO(), w.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case cronJobKind:
+		var resource schema.GroupVersionResource
+		if resource, err = linkerd.cronJobResource(); err == nil {
+			_, err = linkerd.DynamicKubeClient.Resource(resource).Namespace(w.Namespace).Patch(context.TODO(), w.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+		}
+	case jobKind:
+		err = fmt.Errorf("the pod template of a job is immutable")
+	default:
+		err = fmt.Errorf("unsupported workload kind %s", w.Kind)
+	}
+	return err
+}
+
+// podTemplate returns the pod template of the workload
+func (linkerd *Linkerd) podTemplate(w workload) (*corev1.PodTemplateSpec, error) {
+	apps := linkerd.KubeClient.AppsV1()
+	switch w.Kind {
+	case deploymentKind:
+		d, err := apps.Deployments(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &d.Spec.Template, nil
+	case statefulSetKind:
+		s, err := apps.StatefulSets(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &s.Spec.Template, nil
+	case daemonSetKind:
+		ds, err := apps.DaemonSets(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &ds.Spec.Template, nil
+	case jobKind:
+		j, err := linkerd.KubeClient.BatchV1().Jobs(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &j.Spec.Template, nil
+	case cronJobKind:
+		resource, err := linkerd.cronJobResource()
+		if err != nil {
+			return nil, err
+		}
+		cj, err := linkerd.DynamicKubeClient.Resource(resource).Namespace(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		raw, _, err := unstructured.NestedMap(cj.Object, "spec", "jobTemplate", "spec", "template")
+		if err != nil {
+			return nil, err
+		}
+		template := &corev1.PodTemplateSpec{}
+		if err = runtime.DefaultUnstructuredConverter.FromUnstructured(raw, template); err != nil {
+			return nil, err
+		}
+		return template, nil
+	}
+
+	return nil, fmt.Errorf("unsupported workload kind %s", w.Kind)
+}
+
+// recreateJob replaces the job with a copy carrying the inject annotation, as the pod
+// template of a job is immutable. Completed jobs and jobs with active pods are left alone
+// rather than run again, or interrupted
+func (linkerd *Linkerd) recreateJob(w workload, value string) error {
+	client := linkerd.KubeClient.BatchV1().Jobs(w.Namespace)
+	job, err := client.Get(context.TODO(), w.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if job.Status.CompletionTime != nil {
+		return fmt.Errorf("job has already completed, the annotation only applies to new jobs")
+	}
+	if job.Status.Active > 0 {
+		return fmt.Errorf("not restartable, the job has %d active pods", job.Status.Active)
+	}
+	if job.Spec.Template.Annotations[injectAnnotation] == value {
+		return nil
+	}
+
+	propagation := metav1.DeletePropagationForeground
+	if err = client.Delete(context.TODO(), w.Name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		return err
+	}
+	err = wait.PollImmediate(rolloutPollInterval, defaultRolloutTimeout, func() (bool, error) {
+		_, err := client.Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if kubeerror.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return err
+	}
+
+	// The job controller generates the selector of the new job again
+	replacement := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        job.Name,
+			Namespace:   job.Namespace,
+			Labels:      job.Labels,
+			Annotations: job.Annotations,
+		},
+		Spec: job.Spec,
+	}
+	replacement.Spec.Selector = nil
+	replacement.Spec.ManualSelector = nil
+	delete(replacement.Spec.Template.Labels, jobControllerUIDLabel)
+	delete(replacement.Spec.Template.Labels, jobNameLabel)
+	if replacement.Spec.Template.Annotations == nil {
+		replacement.Spec.Template.Annotations = map[string]string{}
+	}
+	replacement.Spec.Template.Annotations[injectAnnotation] = value
+
+	_, err = client.Create(context.TODO(), replacement, metav1.CreateOptions{})
+	return err
+}
+
+// selectWorkloads returns the named workloads of the namespace, or those matching the selector, or all of them
+func (linkerd *Linkerd) selectWorkloads(namespace string, names []string, selector string) ([]workload, error) {
+	all, err := linkerd.listNamespaceWorkloads(namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return all, nil
+	}
+
+	workloads := []workload{}
+	for _, name := range names {
+		kind, n := "", name
+		if i := strings.Index(name, "/"); i >= 0 {
+			kind, n = name[:i], name[i+1:]
+		}
+
+		found := false
+		for _, w := range all {
+			if w.Name == n && (kind == "" || strings.EqualFold(kind, w.Kind)) {
+				workloads = append(workloads, w)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("workload %s not found in namespace %s", name, namespace)
+		}
+	}
+	return workloads, nil
+}
+
+// listNamespaceWorkloads returns the injectable workloads of the namespace matching the selector
+func (linkerd *Linkerd) listNamespaceWorkloads(namespace, selector string) ([]workload, error) {
+	opts := metav1.ListOptions{LabelSelector: selector}
+	workloads := []workload{}
+
+	apps := linkerd.KubeClient.AppsV1()
+	deployments, err := apps.Deployments(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		workloads = append(workloads, workload{Kind: deploymentKind, Namespace: d.Namespace, Name: d.Name})
+	}
+	statefulSets, err := apps.StatefulSets(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		workloads = append(workloads, workload{Kind: statefulSetKind, Namespace: s.Namespace, Name: s.Name})
+	}
+	daemonSets, err := apps.DaemonSets(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, ds := range daemonSets.Items {
+		workloads = append(workloads, workload{Kind: daemonSetKind, Namespace: ds.Namespace, Name: ds.Name})
+	}
+
+	jobs, err := linkerd.KubeClient.BatchV1().Jobs(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range jobs.Items {
+		// Jobs created by cronjobs are covered by their cronjob
+		if owner := metav1.GetControllerOf(&j); owner != nil && owner.Kind == cronJobKind {
+			continue
+		}
+		workloads = append(workloads, workload{Kind: jobKind, Namespace: j.Namespace, Name: j.Name})
+	}
+	// A cluster without a cronjobs API has no cronjobs
+	resource, err := linkerd.cronJobResource()
+	if kubeerror.IsNotFound(err) {
+		return workloads, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cronJobs, err := linkerd.DynamicKubeClient.Resource(resource).Namespace(namespace).List(context.TODO(), opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, cj := range cronJobs.Items {
+		workloads = append(workloads, workload{Kind: cronJobKind, Namespace: cj.GetNamespace(), Name: cj.GetName()})
+	}
+
+	return workloads, nil
+}
+
+// cronJobResource returns the newest version of the cronjobs API served by the cluster,
+// a NotFound error if it serves none
+func (linkerd *Linkerd) cronJobResource() (schema.GroupVersionResource, error) {
+	for _, r := range cronJobResources {
+		resources, err := linkerd.KubeClient.Discovery().ServerResourcesForGroupVersion(r.GroupVersion().String())
+		if kubeerror.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return r, err
+		}
+		for _, api := range resources.APIResources {
+			if api.Name == r.Resource {
+				return r, nil
+			}
+		}
+	}
+	return schema.GroupVersionResource{}, kubeerror.NewNotFound(schema.GroupResource{Group: "batch", Resource: "cronjobs"}, "")
+}
@@ -0,0 +1,167 @@
+package linkerd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// serveJobsOnly serves batch/v1 without cronjobs, as clusters before Kubernetes 1.21 do
+func serveJobsOnly(cluster *fakeCluster) {
+	cluster.hook("GET", "/apis/batch/v1", func(w http.ResponseWriter, r *http.Request) {
+		list := discoveryResources("batch/v1")
+		list["resources"] = []interface{}{list["resources"].([]interface{})[0]}
+		writeJSON(w, http.StatusOK, list)
+	})
+}
+
+func TestCronJobResource(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(*fakeCluster)
+		version string
+	}{
+		{name: "batch/v1", prepare: func(*fakeCluster) {}, version: "v1"},
+		{name: "batch/v1beta1", prepare: serveJobsOnly, version: "v1beta1"},
+		{name: "none", prepare: func(c *fakeCluster) {
+			serveJobsOnly(c)
+			c.disable("batch/v1beta1")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkerd, cluster := newFakeLinkerd(t)
+			tt.prepare(cluster)
+
+			resource, err := linkerd.cronJobResource()
+			if tt.version == "" {
+				if !kubeerror.IsNotFound(err) {
+					t.Errorf("got %v, want a NotFound error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resource.Version != tt.version {
+				t.Errorf("got %s, want %s", resource.Version, tt.version)
+			}
+		})
+	}
+}
+
+func TestListNamespaceWorkloads(t *testing.T) {
+	linkerd, cluster := newFakeLinkerd(t)
+	serveJobsOnly(cluster)
+	cluster.disable("batch/v1beta1")
+
+	jobs := linkerd.KubeClient.BatchV1().Jobs("bookapp")
+	isController := true
+	for _, j := range []*batchv1.Job{
+		{ObjectMeta: metav1.ObjectMeta{Name: "migrate"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "backup-1", OwnerReferences: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: cronJobKind, Name: "backup", Controller: &isController}}}},
+	} {
+		if _, err := jobs.Create(context.TODO(), j, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	workloads, err := linkerd.listNamespaceWorkloads("bookapp", "")
+	if err != nil {
+		t.Fatalf("cluster without a cronjobs API: %s", err)
+	}
+	if len(workloads) != 1 || workloads[0].Kind != jobKind || workloads[0].Name != "migrate" {
+		t.Errorf("got %v, want the job migrate only", workloads)
+	}
+}
+
+func TestInjectJob(t *testing.T) {
+	w := workload{Kind: jobKind, Namespace: "bookapp", Name: "migrate"}
+	newJob := func(t *testing.T, status batchv1.JobStatus) (*Linkerd, *fakeCluster) {
+		linkerd, cluster := newFakeLinkerd(t)
+		job := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: w.Name},
+			Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{jobNameLabel: w.Name, jobControllerUIDLabel: "1"}},
+			}},
+			Status: status,
+		}
+		if _, err := linkerd.KubeClient.BatchV1().Jobs(w.Namespace).Create(context.TODO(), job, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		return linkerd, cluster
+	}
+
+	t.Run("without rerun", func(t *testing.T) {
+		linkerd, cluster := newJob(t, batchv1.JobStatus{})
+		result := linkerd.injectWorkload(w, injectEnabled, time.Second, false, false)
+		if result.Ok || !strings.Contains(result.Message, "rerunJobs") {
+			t.Errorf("got %+v", result)
+		}
+		if m := cluster.mutations(); len(m) > 1 {
+			t.Errorf("job was touched: %v", m)
+		}
+	})
+
+	t.Run("rerun", func(t *testing.T) {
+		linkerd, _ := newJob(t, batchv1.JobStatus{})
+		if err := linkerd.annotateWorkload(w, injectEnabled); err != nil {
+			t.Fatal(err)
+		}
+		job, err := linkerd.KubeClient.BatchV1().Jobs(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := job.Spec.Template
+		if template.Annotations[injectAnnotation] != injectEnabled {
+			t.Errorf("got annotations %v", template.Annotations)
+		}
+		if _, ok := template.Labels[jobControllerUIDLabel]; ok {
+			t.Errorf("recreated job kept the labels of the job controller: %v", template.Labels)
+		}
+	})
+
+	t.Run("completed", func(t *testing.T) {
+		now := metav1.Now()
+		linkerd, cluster := newJob(t, batchv1.JobStatus{CompletionTime: &now, Succeeded: 1})
+		if err := linkerd.annotateWorkload(w, injectEnabled); err == nil {
+			t.Error("expected an error")
+		}
+		if m := cluster.mutations(); len(m) > 1 {
+			t.Errorf("completed job was touched: %v", m)
+		}
+	})
+}
+
+func TestAnnotateCronJob(t *testing.T) {
+	linkerd, cluster := newFakeLinkerd(t)
+	if _, err := linkerd.KubeClient.CoreV1().Namespaces().Create(context.TODO(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bookapp"}}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	cronJob := &unstructured.Unstructured{}
+	cronJob.SetAPIVersion("batch/v1")
+	cronJob.SetKind(cronJobKind)
+	cronJob.SetName("backup")
+	if _, err := linkerd.DynamicKubeClient.Resource(cronJobResources[0]).Namespace("bookapp").Create(context.TODO(), cronJob, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := linkerd.injectWorkload(workload{Kind: cronJobKind, Namespace: "bookapp", Name: "backup"}, injectEnabled, time.Second, false, false)
+	if !result.Ok {
+		t.Fatalf("got %+v", result)
+	}
+	obj := cluster.object("batch/v1", "cronjobs", "bookapp", "backup")
+	value, _, _ := unstructured.NestedString(obj, "spec", "jobTemplate", "spec", "template", "metadata", "annotations", injectAnnotation)
+	if value != injectEnabled {
+		t.Errorf("got inject annotation %q, want %q", value, injectEnabled)
+	}
+}
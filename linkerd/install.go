@@ -3,21 +3,37 @@ package linkerd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/exec"
 	"path"
 	"runtime"
+	"strings"
 
 	"github.com/layer5io/meshery-adapter-library/adapter"
 	"github.com/layer5io/meshery-adapter-library/status"
 	"github.com/layer5io/meshery-linkerd/internal/config"
 	mesherykube "github.com/layer5io/meshkit/utils/kubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func (linkerd *Linkerd) installLinkerd(del bool, version, namespace string) (string, error) {
+// InstallOptions is the optional body of the linkerd install operation
+type InstallOptions struct {
+	// CertManager makes cert-manager manage the identity issuer,
+	// the control plane is installed with the external issuer scheme
+	CertManager *CertManagerOptions `json:"certManager,omitempty"`
+
+	// CNIEnabled installs the control plane without the proxy-init containers,
+	// the linkerd-cni operation must have completed in CNINamespace before
+	CNIEnabled   bool   `json:"cniEnabled,omitempty"`
+	CNINamespace string `json:"cniNamespace,omitempty"`
+}
+
+func (linkerd *Linkerd) installLinkerd(del bool, version, namespace, body string) (string, error) {
 	linkerd.Log.Info(fmt.Sprintf("Requested install of version: %s", version))
 	linkerd.Log.Info(fmt.Sprintf("Requested action is delete: %v", del))
 	linkerd.Log.Info(fmt.Sprintf("Requested action is in namespace: %s", namespace))
@@ -36,7 +52,45 @@ func (linkerd *Linkerd) installLinkerd(del bool, version, namespace string) (str
 		return st, ErrMeshConfig(err)
 	}
 
-	manifest, err := linkerd.fetchManifest(version, namespace, del)
+	opts := InstallOptions{}
+	if err = parseOperationBody(body, &opts); err != nil {
+		return st, err
+	}
+
+	var flags []string
+	if opts.CNIEnabled && !del {
+		if err = linkerd.checkCNIReady(opts.CNINamespace); err != nil {
+			linkerd.Log.Error(err)
+			return st, err
+		}
+		flags = append(flags, "--linkerd-cni-enabled")
+	}
+
+	if opts.CertManager != nil && !del {
+		anchors, err := linkerd.setupCertManagerIssuer(namespace, opts.CertManager)
+		if err != nil {
+			linkerd.Log.Error(err)
+			return st, err
+		}
+
+		file, err := writeTempFile("linkerd-trust-anchors-*.crt", anchors)
+		if err != nil {
+			return st, ErrInstallLinkerd(err)
+		}
+		defer os.Remove(file)
+
+		flags = append(flags, externalIssuerFlag, "--identity-trust-anchors-file", file)
+	}
+
+	// The issuer set up with cert-manager at install time is not part of the manifest
+	if del {
+		if err = linkerd.removeCertManagerIssuer(namespace, opts.CertManager); err != nil {
+			linkerd.Log.Error(err)
+			return st, err
+		}
+	}
+
+	manifest, err := linkerd.fetchManifest(version, namespace, del, flags...)
 	if err != nil {
 		linkerd.Log.Error(ErrInstallLinkerd(err))
 		return st, ErrInstallLinkerd(err)
@@ -49,12 +103,33 @@ func (linkerd *Linkerd) installLinkerd(del bool, version, namespace string) (str
 	}
 
 	if del {
+		if err = linkerd.removeTrustAnchorSecret(namespace, opts.CertManager); err != nil {
+			linkerd.Log.Error(err)
+			return st, err
+		}
 		return status.Removed, nil
 	}
 	return status.Installed, nil
 }
 
-func (linkerd *Linkerd) fetchManifest(version string, namespace string, isDel bool) (string, error) {
+func (linkerd *Linkerd) fetchManifest(version string, namespace string, isDel bool, flags ...string) (string, error) {
+	execCmd := []string{"install"}
+	// The CLI reads the external issuer from the cluster, which rules out --ignore-cluster
+	if !contains(flags, externalIssuerFlag) {
+		execCmd = append(execCmd, "--ignore-cluster")
+	}
+	execCmd = append(execCmd, "--linkerd-namespace", namespace)
+	execCmd = append(execCmd, flags...)
+	if isDel {
+		execCmd = []string{"uninstall", "--linkerd-namespace", namespace}
+	}
+
+	return linkerd.runLinkerdCLI(version, execCmd...)
+}
+
+// runLinkerdCLI runs the linkerd CLI of the given version with the given
+// arguments and returns whatever it writes to stdout
+func (linkerd *Linkerd) runLinkerdCLI(version string, args ...string) (string, error) {
 	var (
 		out bytes.Buffer
 		er  bytes.Buffer
@@ -64,14 +139,10 @@ func (linkerd *Linkerd) fetchManifest(version string, namespace string, isDel bo
 	if err != nil {
 		return "", ErrFetchManifest(err, err.Error())
 	}
-	execCmd := []string{"install", "--ignore-cluster", "--linkerd-namespace", namespace}
-	if isDel {
-		execCmd = []string{"uninstall", "--linkerd-namespace", namespace}
-	}
 
 	// We need a variable executable here hence using nosec
 	// #nosec
-	command := exec.Command(Executable, execCmd...)
+	command := exec.Command(Executable, args...)
 	command.Stdout = &out
 	command.Stderr = &er
 	err = command.Run()
@@ -82,6 +153,37 @@ func (linkerd *Linkerd) fetchManifest(version string, namespace string, isDel bo
 	return out.String(), nil
 }
 
+// getControlPlaneVersion returns the version of the control plane installed in
+// the namespace, as recorded by the linkerd CLI or helm on the identity component
+func (linkerd *Linkerd) getControlPlaneVersion(namespace string) (string, error) {
+	deploy, err := linkerd.KubeClient.AppsV1().Deployments(namespace).Get(context.TODO(), identityDeployment, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	// The annotation has the form "linkerd/cli stable-2.10.2"
+	if createdBy := strings.Fields(deploy.Annotations["linkerd.io/created-by"]); len(createdBy) == 2 {
+		return createdBy[1], nil
+	}
+
+	for _, c := range deploy.Spec.Template.Spec.Containers {
+		if c.Name == "identity" {
+			return imageTag(c.Image), nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to determine control plane version in namespace %s", namespace)
+}
+
+// imageTag returns the tag of a container image reference
+func imageTag(image string) string {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "latest"
+	}
+	return image[i+1:]
+}
+
 func (linkerd *Linkerd) applyManifest(contents []byte, isDel bool, namespace string) error {
 	err := linkerd.MesheryKubeclient.ApplyManifest(contents, mesherykube.ApplyOptions{
 		Namespace: namespace,
@@ -95,6 +197,25 @@ func (linkerd *Linkerd) applyManifest(contents []byte, isDel bool, namespace str
 	return nil
 }
 
+// writeTempFile writes the contents to a new temporary file and returns its path,
+// the caller is responsible for removing it
+func writeTempFile(pattern string, contents []byte) (string, error) {
+	file, err := ioutil.TempFile("", pattern)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = file.Write(contents); err != nil {
+		_ = file.Close()
+		return "", err
+	}
+	if err = file.Close(); err != nil {
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
 // getExecutable looks for the executable in
 // 1. $PATH
 // 2. Root config path
@@ -198,3 +319,13 @@ func installBinary(location, platform string, res *http.Response) error {
 	}
 	return nil
 }
+
+// contains returns true if the value is in the list
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package linkerd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/layer5io/meshery-adapter-library/adapter"
+	"github.com/layer5io/meshery-adapter-library/status"
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	mesherykube "github.com/layer5io/meshkit/utils/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// kubeconfigDebounce is the quiet period after the last change of the kubeconfig
+// before it is reloaded, the file is usually written in several steps
+const kubeconfigDebounce = 500 * time.Millisecond
+
+// watchKubeconfig reloads the kubernetes clients whenever the kubeconfig managed by
+// the adapter changes. The config directory is watched rather than the file itself
+// as the file does not necessarily exist yet and may be replaced rather than written
+func (linkerd *Linkerd) watchKubeconfig() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		linkerd.Log.Warn(ErrKubeconfigReload(err))
+		return
+	}
+	defer watcher.Close()
+
+	if err = watcher.Add(internalconfig.RootPath()); err != nil {
+		linkerd.Log.Warn(ErrKubeconfigReload(err))
+		return
+	}
+
+	kubeconfig := filepath.Clean(internalconfig.KubeConfigPath())
+	debounce := time.NewTimer(kubeconfigDebounce)
+	debounce.Stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != kubeconfig || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+			debounce.Reset(kubeconfigDebounce)
+		case <-debounce.C:
+			if err := linkerd.loadCredentials(); err != nil {
+				linkerd.Log.Error(err)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			linkerd.Log.Warn(ErrKubeconfigReload(err))
+		}
+	}
+}
+
+// loadCredentials resolves the kubernetes credentials along the chain of the explicit
+// kubeconfig, $KUBECONFIG and the in-cluster service account, rebuilds the kubernetes
+// clients from them and swaps them in at once. Running operations work on a snapshot
+// of the handler and keep using the clients they started with. The mesh status of the
+// cluster is discovered again and the permissions of the credentials are verified in the
+// background, the API server may be slow or unreachable
+func (linkerd *Linkerd) loadCredentials() error {
+	source, err := internalconfig.DetectKubeconfigSource()
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if source.Path != "" {
+		data, err = ioutil.ReadFile(source.Path)
+		if err != nil {
+			return ErrKubeconfigReload(err)
+		}
+	}
+
+	cs := linkerd.clusters
+	sum := sha256.Sum256(append([]byte(source.Name), data...))
+	cs.reload.RLock()
+	unchanged := sum == cs.kubeconfigSum
+	cs.reload.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	cfg, err := clientcmd.Load(data)
+	if err != nil {
+		return ErrKubeconfigReload(err)
+	}
+	// An empty kubeconfig makes meshkit fall back to the in-cluster config
+	client, err := mesherykube.New(data)
+	if err != nil {
+		return ErrKubeconfigReload(err)
+	}
+
+	// The linkerd CLI has to use the same credentials
+	if source.Path != "" {
+		err = os.Setenv("KUBECONFIG", source.Path)
+	} else {
+		err = os.Unsetenv("KUBECONFIG")
+	}
+	if err != nil {
+		return ErrKubeconfigReload(err)
+	}
+
+	cs.reload.Lock()
+	linkerd.ClientcmdConfig = cfg
+	linkerd.MesheryKubeclient = client
+	linkerd.KubeClient = client.KubeClient
+	linkerd.DynamicKubeClient = client.DynamicKubeClient
+	linkerd.RestConfig = client.RestConfig
+	cs.kubeconfigSum = sum
+	cs.source = source
+	cs.reload.Unlock()
+
+	// Clients of other contexts may be outdated as well
+	cs.reset()
+
+	linkerd.Log.Info("Using Kubernetes credentials from ", source.Name)
+	linkerd.Config.SetKey(internalconfig.KubeconfigSourceKey, source.Name)
+
+	go linkerd.snapshot().discoverCluster(source)
+	return nil
+}
+
+// discoverCluster verifies the permissions of the credentials and records the mesh status of the cluster
+func (linkerd *Linkerd) discoverCluster(source internalconfig.KubeconfigSource) {
+	if err := linkerd.verifyPermissions(defaultControlPlaneNamespace); err != nil {
+		linkerd.Log.Warn(err)
+	}
+
+	st := status.Installed
+	version, err := linkerd.getControlPlaneVersion(defaultControlPlaneNamespace)
+	if err != nil {
+		st = status.NotInstalled
+		version = status.None
+	}
+	linkerd.recordClusterStatus(st, version)
+
+	// Events can only be streamed once meshery has connected to the adapter
+	if linkerd.Channel != nil {
+		linkerd.StreamInfo(&adapter.Event{
+			Summary: "Kubeconfig reloaded",
+			Details: fmt.Sprintf("Kubernetes clients rebuilt from the %s for context %s, Linkerd is %s (version %s)", source.Name, linkerd.currentContext(), st, version),
+		})
+	}
+}
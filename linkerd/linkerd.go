@@ -2,6 +2,7 @@ package linkerd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/layer5io/meshery-adapter-library/adapter"
@@ -10,22 +11,66 @@ import (
 	"github.com/layer5io/meshery-adapter-library/status"
 	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
 	"github.com/layer5io/meshkit/logger"
+	"sigs.k8s.io/yaml"
 )
 
 // Linkerd is the handler for the adapter
 type Linkerd struct {
 	adapter.Adapter // Type Embedded
+
+	certWatcher *certificateWatcher
+	clusters    *clusterSet
+	faults      *faultTimers
+
+	// contextName is the kubeconfig context the handler operates on,
+	// it is empty for the context the adapter instance was created with
+	contextName string
 }
 
 // New initializes linkerd handler.
 func New(c adapterconfig.Handler, l logger.Handler, kc adapterconfig.Handler) adapter.Handler {
-	return &Linkerd{
+	watcher, err := newCertificateWatcher(internalconfig.GetCertificateWatch())
+	if err != nil {
+		l.Warn(ErrCertificateExpiry(err))
+		watcher, _ = newCertificateWatcher(internalconfig.CertificateWatch)
+	}
+
+	handler := &Linkerd{
 		Adapter: adapter.Adapter{
 			Config:            c,
 			Log:               l,
 			KubeconfigHandler: kc,
 		},
+		certWatcher: watcher,
+		clusters:    newClusterSet(),
+		faults:      newFaultTimers(),
+	}
+	// $KUBECONFIG is pointed at the credentials in use, crucial
+	// when the adapter's running within the containers
+	if err = handler.loadCredentials(); err != nil {
+		l.Warn(err)
 	}
+	go handler.watchKubeconfig()
+
+	return handler
+}
+
+// CreateInstance instantiates the kubernetes clients, starts the certificate expiry watcher
+// and schedules the removal of faults injected before the adapter restarted
+func (linkerd *Linkerd) CreateInstance(kubeconfig []byte, contextName string, ch *chan interface{}) error {
+	linkerd.clusters.reload.Lock()
+	err := linkerd.Adapter.CreateInstance(kubeconfig, contextName, ch)
+	linkerd.clusters.reload.Unlock()
+	if err != nil {
+		return err
+	}
+	linkerd.clusters.reset()
+
+	linkerd.certWatcher.once.Do(func() {
+		go linkerd.watchCertificates()
+	})
+	linkerd.snapshot().resumeFaultRemovalsOnce()
+	return nil
 }
 
 // ApplyOperation applies the operation on linkerd
@@ -42,21 +87,59 @@ func (linkerd *Linkerd) ApplyOperation(ctx context.Context, opReq adapter.Operat
 		Details:     "Operation is not supported",
 	}
 
+	h, err := linkerd.forOperation(opReq)
+	if err != nil {
+		return err
+	}
+
 	switch opReq.OperationName {
 	case internalconfig.LinkerdOperation:
 		go func(hh *Linkerd, ee *adapter.Event) {
 			version := string(operations[opReq.OperationName].Versions[0])
-			stat, err := hh.installLinkerd(opReq.IsDeleteOperation, version, opReq.Namespace)
+			stat, err := hh.installLinkerd(opReq.IsDeleteOperation, version, opReq.Namespace, opReq.CustomBody)
 			if err != nil {
 				e.Summary = fmt.Sprintf("Error while %s Linkerd service mesh", stat)
 				e.Details = err.Error()
 				hh.StreamErr(e, err)
 				return
 			}
+			if opReq.IsDeleteOperation {
+				hh.recordClusterStatus(stat, status.None)
+			} else {
+				hh.recordClusterStatus(stat, version)
+			}
 			ee.Summary = fmt.Sprintf("Linkerd service mesh %s successfully", stat)
 			ee.Details = fmt.Sprintf("The Linkerd service mesh is now %s.", stat)
 			hh.StreamInfo(e)
-		}(linkerd, e)
+		}(h, e)
+	case internalconfig.LinkerdCNI:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			version := string(operations[opReq.OperationName].Versions[0])
+			stat, err := hh.installCNI(opReq.IsDeleteOperation, version, opReq.Namespace)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s Linkerd CNI plugin", stat)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("Linkerd CNI plugin %s successfully", stat)
+			ee.Details = fmt.Sprintf("The Linkerd CNI plugin is now %s.", stat)
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.LinkerdViz, internalconfig.LinkerdJaeger, internalconfig.LinkerdMulticluster:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			ext, _ := internalconfig.GetExtension(opReq.OperationName)
+			stat, err := hh.installExtension(opReq.IsDeleteOperation, ext, opReq.Namespace)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s %s", stat, ext.Description)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("%s %s successfully", ext.Description, stat)
+			ee.Details = fmt.Sprintf("The %s is now %s.", ext.Description, stat)
+			hh.StreamInfo(e)
+		}(h, e)
 	case common.BookInfoOperation, common.HTTPBinOperation, common.ImageHubOperation, common.EmojiVotoOperation:
 		go func(hh *Linkerd, ee *adapter.Event) {
 			appName := operations[opReq.OperationName].AdditionalProperties[common.ServiceName]
@@ -70,7 +153,7 @@ func (linkerd *Linkerd) ApplyOperation(ctx context.Context, opReq adapter.Operat
 			ee.Summary = fmt.Sprintf("%s application %s successfully", appName, stat)
 			ee.Details = fmt.Sprintf("The %s application is now %s.", appName, stat)
 			hh.StreamInfo(e)
-		}(linkerd, e)
+		}(h, e)
 	case common.SmiConformanceOperation:
 		go func(hh *Linkerd, ee *adapter.Event) {
 			name := operations[opReq.OperationName].Description
@@ -93,7 +176,7 @@ func (linkerd *Linkerd) ApplyOperation(ctx context.Context, opReq adapter.Operat
 			ee.Summary = fmt.Sprintf("%s test %s successfully", name, status.Completed)
 			ee.Details = ""
 			hh.StreamInfo(e)
-		}(linkerd, e)
+		}(h, e)
 	case common.CustomOperation:
 		go func(hh *Linkerd, ee *adapter.Event) {
 			stat, err := hh.applyCustomOperation(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
@@ -106,10 +189,10 @@ func (linkerd *Linkerd) ApplyOperation(ctx context.Context, opReq adapter.Operat
 			ee.Summary = fmt.Sprintf("Manifest %s successfully", status.Deployed)
 			ee.Details = ""
 			hh.StreamInfo(e)
-		}(linkerd, e)
+		}(h, e)
 	case internalconfig.AnnotateNamespace:
 		go func(hh *Linkerd, ee *adapter.Event) {
-			err := hh.LoadNamespaceToMesh(opReq.Namespace, opReq.IsDeleteOperation)
+			report, err := hh.annotateNamespace(ee.Operationid, opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
 			if err != nil {
 				e.Summary = fmt.Sprintf("Error while annotating %s", opReq.Namespace)
 				e.Details = err.Error()
@@ -118,12 +201,292 @@ func (linkerd *Linkerd) ApplyOperation(ctx context.Context, opReq adapter.Operat
 			}
 			ee.Summary = "Annotation successful"
 			ee.Details = ""
+			if len(report) > 0 {
+				meshed, unmeshed := 0, 0
+				for _, r := range report {
+					meshed += r.MeshedPods
+					unmeshed += r.UnmeshedPods
+				}
+				details, _ := json.Marshal(report)
+				ee.Summary = fmt.Sprintf("Annotation successful, %d pods meshed and %d unmeshed", meshed, unmeshed)
+				ee.Details = string(details)
+			}
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.InjectWorkloads:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.injectWorkloads(ee.Operationid, opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while injecting workloads in %s", opReq.Namespace)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			meshed := 0
+			for _, r := range report {
+				if r.Ok {
+					meshed++
+				}
+			}
+			details, _ := json.Marshal(report)
+			ee.Details = string(details)
+			if meshed < len(report) {
+				e.Summary = fmt.Sprintf("%d of %d workloads injected successfully", meshed, len(report))
+				hh.StreamErr(e, ErrInjectWorkloads(fmt.Errorf("%d workloads failed", len(report)-meshed)))
+				return
+			}
+			ee.Summary = fmt.Sprintf("%d workloads injected successfully", len(report))
+			if opReq.IsDeleteOperation {
+				ee.Summary = fmt.Sprintf("%d workloads uninjected successfully", len(report))
+			}
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.RotateIssuer:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			stat, err := hh.rotateIssuer(ee.Operationid, opReq.Namespace, opReq.CustomBody)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s identity issuer", stat)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("Identity issuer %s successfully", stat)
+			ee.Details = "The identity issuer certificate has been rotated."
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.RotateTrustAnchor:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			stat, err := hh.rotateTrustAnchor(ee.Operationid, opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s trust anchor", stat)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("Trust anchor rotation %s successfully", stat)
+			ee.Details = "The trust anchor rotation has finished."
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.MulticlusterLink:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			stat, err := hh.linkClusters(ee.Operationid, opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s clusters", stat)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("Multicluster link %s successfully", stat)
+			ee.Details = "The service mirror controller is healthy."
+			if opReq.IsDeleteOperation {
+				ee.Details = "The multicluster link has been removed."
+			}
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.ExportServices:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.exportServices(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while exporting services in %s", opReq.Namespace)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			details, _ := json.Marshal(report)
+			ee.Summary = fmt.Sprintf("%d services exported successfully", len(report))
+			if opReq.IsDeleteOperation {
+				ee.Summary = fmt.Sprintf("%d services unexported successfully", len(report))
+			}
+			ee.Details = string(details)
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.UpgradeProxies:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.upgradeProxies(ee.Operationid, opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = "Error while upgrading data plane proxies"
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			if opReq.IsDeleteOperation {
+				ee.Summary = "Data plane upgrade abandoned"
+				ee.Details = "The persisted progress of the data plane upgrade has been removed."
+				hh.StreamInfo(e)
+				return
+			}
+			details, _ := json.Marshal(report)
+			ee.Summary = fmt.Sprintf("Proxies of %d workloads upgraded to %s successfully", len(report.Upgraded), report.Version)
+			ee.Details = string(details)
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.ProxyConfig:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.configureProxies(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while configuring proxies in %s", opReq.Namespace)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			details, _ := json.Marshal(report)
+			ee.Summary = fmt.Sprintf("Proxy configuration of %d targets updated successfully", len(report))
+			ee.Details = string(details)
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.ServiceProfile:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			stat, manifest, err := hh.applyServiceProfile(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s service profile", stat)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("Service profile %s successfully", stat)
+			ee.Details = manifest
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.RoutePolicy:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			diff, err := hh.applyRoutePolicy(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while configuring routes in %s", opReq.Namespace)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = "Route policies updated successfully"
+			ee.Details = diff
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.Canary:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			stat, err := hh.runCanary(ee.Operationid, opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s canary", stat)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("Canary %s successfully", stat)
+			ee.Details = fmt.Sprintf("The canary is now %s.", stat)
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.FaultInjection:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			stat, details, err := hh.injectFault(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while %s fault", stat)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			ee.Summary = fmt.Sprintf("Fault %s successfully", stat)
+			ee.Details = details
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.AuthorizationPolicy:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.applyAuthorizationPolicy(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while authorizing clients in %s", opReq.Namespace)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			details, _ := json.Marshal(report)
+			ee.Summary = policySummary(report, opReq.IsDeleteOperation)
+			ee.Details = string(details)
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.DefaultDenyPolicy:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.applyDefaultDenyPolicy(opReq.Namespace, opReq.CustomBody, opReq.IsDeleteOperation)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while denying inbound traffic in %s", opReq.Namespace)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			details, _ := json.Marshal(report)
+			ee.Summary = policySummary(report, opReq.IsDeleteOperation)
+			ee.Details = string(details)
 			hh.StreamInfo(e)
-		}(linkerd, e)
+		}(h, e)
+	case internalconfig.MeshStatus:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.meshStatus(opReq.CustomBody)
+			if err != nil {
+				e.Summary = "Error while collecting mesh status"
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			details, _ := json.Marshal(report)
+			ee.Summary = fmt.Sprintf("%d pods meshed, %d unmeshed, %d stale proxies, %d active faults", report.MeshedPods, report.UnmeshedPods, len(report.StaleProxies), len(report.Faults))
+			ee.Details = string(details)
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.MTLSVerify:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.verifyMTLS(opReq.CustomBody)
+			if err != nil {
+				e.Summary = "Error while verifying mutual TLS"
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			details, _ := json.Marshal(report)
+			failed := 0
+			for _, ns := range report.Namespaces {
+				if !ns.Passed {
+					failed++
+				}
+			}
+			ee.Summary = fmt.Sprintf("mTLS verification passed in %d of %d namespaces", len(report.Namespaces)-failed, len(report.Namespaces))
+			ee.Details = string(details)
+			if !report.Passed {
+				hh.StreamErr(e, ErrMTLSVerify(fmt.Errorf("%d namespaces have edges which are not mutually authenticated", failed)))
+				return
+			}
+			hh.StreamInfo(e)
+		}(h, e)
+	case internalconfig.Stats:
+		go func(hh *Linkerd, ee *adapter.Event) {
+			report, err := hh.stats(opReq.Namespace, opReq.CustomBody)
+			if err != nil {
+				e.Summary = fmt.Sprintf("Error while querying stats of %s", opReq.Namespace)
+				e.Details = err.Error()
+				hh.StreamErr(e, err)
+				return
+			}
+			details, _ := json.Marshal(report)
+			ee.Summary = fmt.Sprintf("Stats of %d %s rows in %s over %s", len(report.Rows), report.By, report.Namespace, report.Window)
+			ee.Details = string(details)
+			hh.StreamInfo(e)
+		}(h, e)
 	default:
 		e.Summary = "Invalid Request"
-		linkerd.StreamErr(e, ErrOpInvalid)
+		h.StreamErr(e, ErrOpInvalid)
 	}
 
 	return nil
 }
+
+// streamProgress streams an informational event for a single step of a long running operation
+func (linkerd *Linkerd) streamProgress(opID, summary, details string) {
+	linkerd.StreamInfo(&adapter.Event{
+		Operationid: opID,
+		Summary:     summary,
+		Details:     details,
+	})
+}
+
+// parseOperationBody decodes the YAML or JSON body of an operation request into v
+func parseOperationBody(body string, v interface{}) error {
+	if err := yaml.Unmarshal([]byte(body), v); err != nil {
+		return ErrParseOperationBody(err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MeshStatusOptions is the body of the mesh-status operation
+type MeshStatusOptions struct {
+	// Namespaces to report on, all namespaces if empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ControlPlaneNamespace defaults to linkerd
+	ControlPlaneNamespace string `json:"controlPlaneNamespace,omitempty"`
+}
+
+// MeshStatusReport is the mesh coverage of the cluster
+type MeshStatusReport struct {
+	ControlPlaneVersion string              `json:"controlPlaneVersion"`
+	MeshedPods          int                 `json:"meshedPods"`
+	UnmeshedPods        int                 `json:"unmeshedPods"`
+	Namespaces          []NamespaceCoverage `json:"namespaces"`
+	// StaleProxies are the proxies whose version differs from the control plane
+	StaleProxies []PodCoverage `json:"staleProxies"`
+	// OptedOut are the workloads which disabled proxy injection
+	OptedOut []string `json:"optedOut"`
+	// Faults are the faults injected into the traffic of the reported namespaces,
+	// missing if they could not be listed
+	Faults []ActiveFault `json:"faults,omitempty"`
+}
+
+// NamespaceCoverage is the mesh coverage of a single namespace
+type NamespaceCoverage struct {
+	Namespace string `json:"namespace"`
+	// Inject is the value of the linkerd.io/inject annotation of the namespace
+	Inject string        `json:"inject,omitempty"`
+	Pods   []PodCoverage `json:"pods"`
+}
+
+// PodCoverage reports whether a pod is meshed and the version of its proxy
+type PodCoverage struct {
+	Pod          string `json:"pod"`
+	Meshed       bool   `json:"meshed"`
+	ProxyVersion string `json:"proxyVersion,omitempty"`
+}
+
+// meshStatus walks the namespaces and their running pods and reports which of them are
+// meshed, which proxy versions they run and which workloads opted out of injection
+func (linkerd *Linkerd) meshStatus(body string) (*MeshStatusReport, error) {
+	opts := MeshStatusOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	if opts.ControlPlaneNamespace == "" {
+		opts.ControlPlaneNamespace = defaultControlPlaneNamespace
+	}
+
+	version, err := linkerd.getControlPlaneVersion(opts.ControlPlaneNamespace)
+	if kubeerror.IsNotFound(err) {
+		return nil, ErrControlPlaneNotFound(opts.ControlPlaneNamespace)
+	}
+	if err != nil {
+		return nil, ErrMeshStatus(err)
+	}
+
+	namespaces := []corev1.Namespace{}
+	if len(opts.Namespaces) == 0 {
+		list, err := linkerd.KubeClient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, ErrMeshStatus(err)
+		}
+		namespaces = list.Items
+	}
+	for _, name := range opts.Namespaces {
+		ns, err := linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, ErrMeshStatus(err)
+		}
+		namespaces = append(namespaces, *ns)
+	}
+
+	report := &MeshStatusReport{
+		ControlPlaneVersion: version,
+		Namespaces:          []NamespaceCoverage{},
+		StaleProxies:        []PodCoverage{},
+		OptedOut:            []string{},
+	}
+	optedOut := map[string]bool{}
+	for _, ns := range namespaces {
+		coverage := NamespaceCoverage{
+			Namespace: ns.Name,
+			Inject:    ns.Annotations[injectAnnotation],
+			Pods:      []PodCoverage{},
+		}
+
+		pods, err := linkerd.KubeClient.CoreV1().Pods(ns.Name).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, ErrMeshStatus(err)
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+				continue
+			}
+
+			pc := PodCoverage{Pod: fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)}
+			if proxy := proxyContainer(pod); proxy != nil {
+				pc.Meshed = true
+				pc.ProxyVersion = imageTag(proxy.Image)
+				report.MeshedPods++
+				if pc.ProxyVersion != version {
+					report.StaleProxies = append(report.StaleProxies, pc)
+				}
+			} else {
+				report.UnmeshedPods++
+			}
+			coverage.Pods = append(coverage.Pods, pc)
+
+			if pod.Annotations[injectAnnotation] == injectDisabled {
+				name := pc.Pod
+				if w, ok, err := linkerd.podOwner(pod); err == nil && ok {
+					name = w.String()
+				}
+				optedOut[name] = true
+			}
+		}
+		report.Namespaces = append(report.Namespaces, coverage)
+	}
+
+	for name := range optedOut {
+		report.OptedOut = append(report.OptedOut, name)
+	}
+	sort.Strings(report.OptedOut)
+
+	// Faults are optional, e.g. the credentials may lack access to the SMI resources
+	faults, err := linkerd.activeFaults()
+	if err != nil {
+		linkerd.Log.Warn(err)
+		return report, nil
+	}
+	report.Faults = []ActiveFault{}
+	for _, f := range faults {
+		for _, ns := range namespaces {
+			if f.Namespace == ns.Name {
+				report.Faults = append(report.Faults, f)
+				break
+			}
+		}
+	}
+	return report, nil
+}
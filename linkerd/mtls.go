@@ -0,0 +1,209 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultMTLSWindow = 5 * time.Minute
+	inboundTLSQuery   = `sum(rate(request_total{direction="inbound"%s}[%s])) by (namespace, pod, client_id, tls, target_addr)`
+
+	plaintextViolation  = "plaintext"
+	noIdentityViolation = "no client identity"
+)
+
+// MTLSVerifyOptions is the body of the mtls-verify operation
+type MTLSVerifyOptions struct {
+	// Namespaces whose inbound traffic is verified, all namespaces if empty
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Window is the time range the traffic is taken from, defaults to five minutes, at least 20s
+	Window string `json:"window,omitempty"`
+	// ControlPlaneNamespace defaults to linkerd
+	ControlPlaneNamespace string `json:"controlPlaneNamespace,omitempty"`
+
+	PrometheusOptions `json:",inline"`
+}
+
+// MTLSReport is the outcome of the mtls-verify operation
+type MTLSReport struct {
+	Passed     bool                   `json:"passed"`
+	Namespaces []NamespaceMTLSSummary `json:"namespaces"`
+	Edges      []MTLSEdge             `json:"edges"`
+}
+
+// NamespaceMTLSSummary summarizes the edges into the meshed pods of a namespace, a
+// namespace without observed traffic apart from probes has no data and does not pass
+type NamespaceMTLSSummary struct {
+	Namespace  string `json:"namespace"`
+	Edges      int    `json:"edges"`
+	Probes     int    `json:"probes"`
+	Violations int    `json:"violations"`
+	NoData     bool   `json:"noData,omitempty"`
+	Passed     bool   `json:"passed"`
+}
+
+// MTLSEdge is the traffic from a source to a destination identity
+type MTLSEdge struct {
+	// Source is the identity of the client, empty if it did not present one
+	Source string `json:"source"`
+	// Destination is the identity of the server, or the pod if it is gone
+	Destination string  `json:"destination"`
+	Namespace   string  `json:"namespace"`
+	TLS         bool    `json:"tls"`
+	RequestRate float64 `json:"requestRate"`
+	// Probe is set for the plaintext requests of the kubelet to the probe ports of the pod
+	Probe bool `json:"probe,omitempty"`
+	// Violations are the reasons the edge is not mutually authenticated
+	Violations []string `json:"violations,omitempty"`
+}
+
+// verifyMTLS builds the edges between the identities from the inbound traffic the meshed
+// proxies reported to Prometheus and flags those which are not mutually authenticated
+func (linkerd *Linkerd) verifyMTLS(body string) (*MTLSReport, error) {
+	opts := MTLSVerifyOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	if opts.ControlPlaneNamespace == "" {
+		opts.ControlPlaneNamespace = defaultControlPlaneNamespace
+	}
+	window, err := parseQueryWindow(opts.Window, defaultMTLSWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := linkerd.getLinkerdConfigValues(opts.ControlPlaneNamespace)
+	if kubeerror.IsNotFound(err) {
+		return nil, ErrControlPlaneNotFound(opts.ControlPlaneNamespace)
+	}
+	if err != nil {
+		return nil, ErrMTLSVerify(err)
+	}
+
+	prom, err := linkerd.prometheus(opts.PrometheusOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer prom.Close()
+
+	filter := ""
+	if len(opts.Namespaces) > 0 {
+		filter = fmt.Sprintf(`, namespace=~"%s"`, strings.Join(opts.Namespaces, "|"))
+	}
+	samples, err := prom.query(fmt.Sprintf(inboundTLSQuery, filter, fmt.Sprintf("%ds", int(window.Seconds()))))
+	if err != nil {
+		return nil, err
+	}
+
+	// Pods are looked up once per namespace to resolve the identity of their proxies
+	pods := map[string][]corev1.Pod{}
+	edges := map[string]*MTLSEdge{}
+	for _, s := range samples {
+		if s.Value == 0 {
+			continue
+		}
+		ns := s.Metric["namespace"]
+		if _, ok := pods[ns]; !ok {
+			list, err := linkerd.KubeClient.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return nil, ErrMTLSVerify(err)
+			}
+			pods[ns] = list.Items
+		}
+
+		edge := MTLSEdge{
+			Source:      s.Metric["client_id"],
+			Destination: fmt.Sprintf("%s/%s", ns, s.Metric["pod"]),
+			Namespace:   ns,
+			TLS:         s.Metric["tls"] == "true",
+		}
+		if pod := findPod(pods[ns], s.Metric["pod"]); pod != nil {
+			edge.Destination = proxyIdentity(pod, opts.ControlPlaneNamespace, values.IdentityTrustDomain)
+			port := s.Metric["target_addr"]
+			if i := strings.LastIndex(port, ":"); i >= 0 {
+				port = port[i+1:]
+			}
+			edge.Probe = !edge.TLS && edge.Source == "" && isProbePort(pod, port)
+		}
+
+		key := fmt.Sprintf("%s|%s|%t|%t", edge.Source, edge.Destination, edge.TLS, edge.Probe)
+		if e, ok := edges[key]; ok {
+			e.RequestRate += s.Value
+			continue
+		}
+		edge.RequestRate = s.Value
+		if !edge.TLS && !edge.Probe {
+			edge.Violations = append(edge.Violations, plaintextViolation)
+		}
+		if edge.Source == "" && !edge.Probe {
+			edge.Violations = append(edge.Violations, noIdentityViolation)
+		}
+		edges[key] = &edge
+	}
+
+	report := &MTLSReport{Passed: true, Namespaces: []NamespaceMTLSSummary{}, Edges: []MTLSEdge{}}
+	summaries := map[string]*NamespaceMTLSSummary{}
+	for _, ns := range opts.Namespaces {
+		summaries[ns] = &NamespaceMTLSSummary{Namespace: ns}
+	}
+	for _, e := range edges {
+		report.Edges = append(report.Edges, *e)
+
+		summary, ok := summaries[e.Namespace]
+		if !ok {
+			summary = &NamespaceMTLSSummary{Namespace: e.Namespace}
+			summaries[e.Namespace] = summary
+		}
+		if e.Probe {
+			summary.Probes++
+			continue
+		}
+		summary.Edges++
+		if len(e.Violations) > 0 {
+			summary.Violations++
+		}
+	}
+	for _, summary := range summaries {
+		summary.NoData = summary.Edges == 0
+		summary.Passed = !summary.NoData && summary.Violations == 0
+		if !summary.Passed {
+			report.Passed = false
+		}
+		report.Namespaces = append(report.Namespaces, *summary)
+	}
+
+	sort.Slice(report.Edges, func(i, j int) bool {
+		a, b := report.Edges[i], report.Edges[j]
+		if a.Destination != b.Destination {
+			return a.Destination < b.Destination
+		}
+		return a.Source < b.Source
+	})
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+	return report, nil
+}
+
+// isProbePort returns true if the port is the port of an HTTP probe of a container of the pod
+func isProbePort(pod *corev1.Pod, port string) bool {
+	for _, c := range pod.Spec.Containers {
+		for _, probe := range []*corev1.Probe{c.LivenessProbe, c.ReadinessProbe, c.StartupProbe} {
+			if probe == nil || probe.HTTPGet == nil {
+				continue
+			}
+			if portMatches(pod, probe.HTTPGet.Port, port) {
+				return true
+			}
+		}
+	}
+	return false
+}
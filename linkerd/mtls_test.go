@@ -0,0 +1,108 @@
+package linkerd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/layer5io/meshkit/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestVerifyMTLS(t *testing.T) {
+	linkerd, _ := newFakeLinkerd(t)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: linkerdConfigMap},
+		Data:       map[string]string{"values": "identityTrustDomain: cluster.local\n"},
+	}
+	if _, err := linkerd.KubeClient.CoreV1().ConfigMaps("linkerd").Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	probe := &corev1.Probe{}
+	probe.HTTPGet = &corev1.HTTPGetAction{Port: intstr.FromInt(9990)}
+	for _, pod := range []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "bookapp", Name: "books-1"},
+			Spec:       corev1.PodSpec{ServiceAccountName: "books", Containers: []corev1.Container{{Name: "books", ReadinessProbe: probe}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "bookapp", Name: "authors-1"},
+			Spec:       corev1.PodSpec{ServiceAccountName: "authors", Containers: []corev1.Container{{Name: "authors"}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "emojivoto", Name: "emoji-1"},
+			Spec:       corev1.PodSpec{ServiceAccountName: "emoji", Containers: []corev1.Container{{Name: "emoji", LivenessProbe: probe}}},
+		},
+	} {
+		if _, err := linkerd.KubeClient.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	web := "web.bookapp.serviceaccount.identity.linkerd.cluster.local"
+	books := "books.bookapp.serviceaccount.identity.linkerd.cluster.local"
+	authors := "authors.bookapp.serviceaccount.identity.linkerd.cluster.local"
+	prom := newFakePrometheus(t, []map[string]string{
+		// The requests to two ports of books are a single edge
+		{"namespace": "bookapp", "pod": "books-1", "client_id": web, "tls": "true", "target_addr": "10.0.0.1:7002"},
+		{"namespace": "bookapp", "pod": "books-1", "client_id": web, "tls": "true", "target_addr": "10.0.0.1:7003"},
+		{"namespace": "bookapp", "pod": "books-1", "tls": "false", "target_addr": "10.0.0.1:9990"},
+		{"namespace": "bookapp", "pod": "authors-1", "tls": "false", "target_addr": "10.0.0.2:7001"},
+		{"namespace": "bookapp", "pod": "gone", "client_id": web, "tls": "true", "target_addr": "10.0.0.3:7000"},
+		{"namespace": "emojivoto", "pod": "emoji-1", "tls": "false", "target_addr": "10.0.0.4:9990"},
+	})
+	prom.set("request_total", "2")
+
+	report, err := linkerd.verifyMTLS(`{"namespaces": ["bookapp", "emojivoto"], "prometheusURL": "` + prom.URL + `"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source      string
+		destination string
+		probe       bool
+		rate        float64
+		violations  int
+	}{
+		{destination: authors, rate: 2, violations: 2},
+		{source: web, destination: "bookapp/gone", rate: 2},
+		{destination: books, probe: true, rate: 2},
+		{source: web, destination: books, rate: 4},
+		{destination: "emoji.emojivoto.serviceaccount.identity.linkerd.cluster.local", probe: true, rate: 2},
+	}
+	if len(report.Edges) != len(tests) {
+		t.Fatalf("got edges %+v", report.Edges)
+	}
+	for i, tt := range tests {
+		e := report.Edges[i]
+		if e.Source != tt.source || e.Destination != tt.destination || e.Probe != tt.probe || e.RequestRate != tt.rate || len(e.Violations) != tt.violations {
+			t.Errorf("got edge %+v, want %+v", e, tt)
+		}
+	}
+
+	want := []NamespaceMTLSSummary{
+		{Namespace: "bookapp", Edges: 3, Probes: 1, Violations: 1},
+		{Namespace: "emojivoto", Probes: 1, NoData: true},
+	}
+	if len(report.Namespaces) != len(want) {
+		t.Fatalf("got namespaces %+v", report.Namespaces)
+	}
+	for i := range want {
+		if report.Namespaces[i] != want[i] {
+			t.Errorf("got %+v, want %+v", report.Namespaces[i], want[i])
+		}
+	}
+	if report.Passed {
+		t.Error("report passed with a plaintext edge")
+	}
+}
+
+func TestVerifyMTLSWithoutControlPlane(t *testing.T) {
+	linkerd, _ := newFakeLinkerd(t)
+	_, err := linkerd.verifyMTLS(`{"prometheusURL": "http://localhost:9090"}`)
+	if errors.GetCode(err) != ErrControlPlaneNotFoundCode {
+		t.Errorf("got %v, want ErrControlPlaneNotFound", err)
+	}
+}
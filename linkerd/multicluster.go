@@ -0,0 +1,307 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	multiclusterNamespace = "linkerd-multicluster"
+	gatewayDeployment     = "linkerd-gateway"
+	serviceMirrorPrefix   = "linkerd-service-mirror-"
+
+	exportedLabel = "mirror.linkerd.io/exported"
+	mirrorTimeout = time.Minute
+
+	// Mirror status of an exported service in a linked cluster
+	mirrorReady   = "mirrored"
+	mirrorMissing = "missing"
+	mirrorRemoved = "removed"
+	mirrorStale   = "stale"
+)
+
+// invalidLabelChars are the characters not allowed in a DNS-1123 label
+var invalidLabelChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// MulticlusterLinkOptions is the body of the multicluster-link operation
+type MulticlusterLinkOptions struct {
+	// SourceContext is the kubeconfig context of the cluster whose services are mirrored
+	SourceContext string `json:"sourceContext"`
+	// TargetContext is the kubeconfig context of the cluster the link is created in
+	TargetContext string `json:"targetContext"`
+	// ClusterName is the name of the link, it has to be a DNS-1123 label. Defaults
+	// to the source context turned into one
+	ClusterName string `json:"clusterName,omitempty"`
+}
+
+// clusterName returns the name given to a link, or derives one from the context by
+// lowercasing it and replacing the characters not allowed in a DNS-1123 label
+func clusterName(name, context string) (string, error) {
+	if name == "" {
+		name = strings.Trim(invalidLabelChars.ReplaceAllString(strings.ToLower(context), "-"), "-")
+		if len(name) > validation.DNS1123LabelMaxLength {
+			name = strings.TrimRight(name[:validation.DNS1123LabelMaxLength], "-")
+		}
+	}
+	if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid cluster name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
+// linkClusters links the target cluster to the source cluster so that services
+// exported in the source cluster are mirrored into the target cluster
+func (linkerd *Linkerd) linkClusters(opID, namespace, body string, del bool) (string, error) {
+	st := status.Connecting
+	if del {
+		st = status.Removing
+	}
+	if namespace == "" {
+		namespace = defaultControlPlaneNamespace
+	}
+
+	opts := MulticlusterLinkOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return st, err
+	}
+	if opts.SourceContext == "" || opts.TargetContext == "" {
+		return st, ErrMulticlusterLink(fmt.Errorf("sourceContext and targetContext are required"))
+	}
+	// The CLI reads the credentials of the source cluster from a kubeconfig file
+	if linkerd.kubeconfigSource().Path == "" {
+		return st, ErrMulticlusterLink(fmt.Errorf("linking clusters needs a kubeconfig with the contexts of both clusters, the adapter runs with its in-cluster service account"))
+	}
+	name, err := clusterName(opts.ClusterName, opts.SourceContext)
+	if err != nil {
+		return st, ErrMulticlusterLink(err)
+	}
+	if name != opts.ClusterName {
+		linkerd.streamProgress(opID, "Cluster name", fmt.Sprintf("Link named %s after context %s", name, opts.SourceContext))
+	}
+	opts.ClusterName = name
+
+	source, err := linkerd.forContext(opts.SourceContext)
+	if err != nil {
+		return st, err
+	}
+	target, err := linkerd.forContext(opts.TargetContext)
+	if err != nil {
+		return st, err
+	}
+
+	if !del {
+		if err = checkSharedTrustAnchor(source, target, namespace); err != nil {
+			return st, ErrMulticlusterLink(err)
+		}
+		linkerd.streamProgress(opID, "Trust anchor verified", fmt.Sprintf("Contexts %s and %s share a trust anchor", opts.SourceContext, opts.TargetContext))
+
+		ready, err := source.isWorkloadReady(workload{Kind: deploymentKind, Namespace: multiclusterNamespace, Name: gatewayDeployment})
+		if err != nil || !ready {
+			return st, ErrMulticlusterLink(fmt.Errorf("multicluster gateway is not ready in context %s", opts.SourceContext))
+		}
+	}
+
+	version, err := source.getControlPlaneVersion(namespace)
+	if err != nil {
+		return st, ErrMulticlusterLink(err)
+	}
+	manifest, err := linkerd.runLinkerdCLI(version,
+		"--kubeconfig", linkerd.kubeconfigSource().Path,
+		"--context", opts.SourceContext,
+		"multicluster", "link",
+		"--cluster-name", opts.ClusterName,
+		"--linkerd-namespace", namespace,
+	)
+	if err != nil {
+		return st, ErrMulticlusterLink(err)
+	}
+	linkerd.streamProgress(opID, "Link credentials generated", fmt.Sprintf("Credentials and gateway configuration generated from context %s", opts.SourceContext))
+
+	// The link resources carry their own namespace
+	if err = target.applyManifest([]byte(manifest), del, ""); err != nil {
+		return st, ErrMulticlusterLink(err)
+	}
+	if del {
+		return status.Removed, nil
+	}
+	linkerd.streamProgress(opID, "Link applied", fmt.Sprintf("Link %s created in context %s", opts.ClusterName, opts.TargetContext))
+
+	mirror := workload{Kind: deploymentKind, Namespace: multiclusterNamespace, Name: serviceMirrorPrefix + opts.ClusterName}
+	if err = target.waitForWorkload(mirror, defaultRolloutTimeout); err != nil {
+		return st, ErrMulticlusterLink(err)
+	}
+
+	return status.Connected, nil
+}
+
+// checkSharedTrustAnchor makes sure the control planes of both clusters have a trust anchor in common
+func checkSharedTrustAnchor(source, target *Linkerd, namespace string) error {
+	sourceAnchors, err := source.getTrustAnchors(namespace)
+	if err != nil {
+		return err
+	}
+	targetAnchors, err := target.getTrustAnchors(namespace)
+	if err != nil {
+		return err
+	}
+
+	for _, anchor := range sourceAnchors {
+		if len(withoutCertificate(targetAnchors, anchor)) < len(targetAnchors) {
+			return nil
+		}
+	}
+	return fmt.Errorf("clusters do not share a trust anchor")
+}
+
+// ExportServicesOptions is the body of the export-services operation. Services are
+// selected by name or by label selector, all the services of the namespace are
+// exported if neither is set
+type ExportServicesOptions struct {
+	Services []string `json:"services,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+
+	// LinkedContexts are the kubeconfig contexts of the clusters linked to this
+	// one, the mirrored services are looked up in them
+	LinkedContexts []string `json:"linkedContexts,omitempty"`
+	// ClusterName is the name of the link to this cluster in the linked
+	// clusters, defaults to the current context named as by multicluster-link
+	ClusterName string `json:"clusterName,omitempty"`
+}
+
+// ServiceMirrorStatus reports whether an exported service is mirrored into the linked clusters
+type ServiceMirrorStatus struct {
+	Service  string            `json:"service"`
+	Exported bool              `json:"exported"`
+	Mirrors  map[string]string `json:"mirrors,omitempty"`
+}
+
+// exportServices labels the selected services for mirroring, or removes the label,
+// and reports whether the mirrored services show up in the linked clusters
+func (linkerd *Linkerd) exportServices(namespace, body string, del bool) ([]ServiceMirrorStatus, error) {
+	opts := ExportServicesOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		return nil, ErrExportService(fmt.Errorf("no namespace given"))
+	}
+	if len(opts.LinkedContexts) > 0 {
+		current := ""
+		if linkerd.ClientcmdConfig != nil {
+			current = linkerd.ClientcmdConfig.CurrentContext
+		}
+		name, err := clusterName(opts.ClusterName, current)
+		if err != nil {
+			return nil, ErrExportService(err)
+		}
+		opts.ClusterName = name
+	}
+
+	services, err := linkerd.selectServices(namespace, opts.Services, opts.Selector)
+	if err != nil {
+		return nil, ErrExportService(err)
+	}
+
+	report := make([]ServiceMirrorStatus, 0, len(services))
+	for i := range services {
+		svc := &services[i]
+		if svc.Labels == nil {
+			svc.Labels = map[string]string{}
+		}
+		svc.Labels[exportedLabel] = "true"
+		if del {
+			delete(svc.Labels, exportedLabel)
+		}
+
+		if _, err = linkerd.KubeClient.CoreV1().Services(namespace).Update(context.TODO(), svc, metav1.UpdateOptions{}); err != nil {
+			return nil, ErrExportService(err)
+		}
+		report = append(report, ServiceMirrorStatus{Service: svc.Name, Exported: !del, Mirrors: map[string]string{}})
+	}
+
+	linked := map[string]*Linkerd{}
+	for _, name := range opts.LinkedContexts {
+		l, err := linkerd.forContext(name)
+		if err != nil {
+			return nil, err
+		}
+		linked[name] = l
+	}
+	waitForMirrors(linked, namespace, opts.ClusterName, report, !del)
+
+	return report, nil
+}
+
+// selectServices returns the named services of the namespace, or those matching
+// the selector, or all of them
+func (linkerd *Linkerd) selectServices(namespace string, names []string, selector string) ([]corev1.Service, error) {
+	client := linkerd.KubeClient.CoreV1().Services(namespace)
+	if len(names) == 0 {
+		list, err := client.List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+
+	services := []corev1.Service{}
+	for _, name := range names {
+		svc, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, *svc)
+	}
+	return services, nil
+}
+
+// waitForMirrors waits for the mirrors of the services to appear in the linked clusters,
+// or to disappear if present is false, and records their status in the report. All the
+// mirrors are polled together under a single deadline
+func waitForMirrors(linked map[string]*Linkerd, namespace, clusterName string, report []ServiceMirrorStatus, present bool) {
+	type mirror struct {
+		context string
+		service int
+		name    string
+	}
+	pending := []mirror{}
+	for name := range linked {
+		for i := range report {
+			pending = append(pending, mirror{context: name, service: i, name: fmt.Sprintf("%s-%s", report[i].Service, clusterName)})
+		}
+	}
+
+	_ = wait.PollImmediate(rolloutPollInterval, mirrorTimeout, func() (bool, error) {
+		remaining := pending[:0]
+		for _, m := range pending {
+			_, err := linked[m.context].KubeClient.CoreV1().Services(namespace).Get(context.TODO(), m.name, metav1.GetOptions{})
+			switch {
+			case present && err == nil:
+				report[m.service].Mirrors[m.context] = mirrorReady
+			case !present && kubeerror.IsNotFound(err):
+				report[m.service].Mirrors[m.context] = mirrorRemoved
+			default:
+				remaining = append(remaining, m)
+			}
+		}
+		pending = remaining
+		return len(pending) == 0, nil
+	})
+
+	for _, m := range pending {
+		report[m.service].Mirrors[m.context] = mirrorStale
+		if present {
+			report[m.service].Mirrors[m.context] = mirrorMissing
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package linkerd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestClusterName(t *testing.T) {
+	tests := []struct {
+		name    string
+		context string
+		want    string
+	}{
+		{name: "east", want: "east"},
+		{context: "kind-east", want: "kind-east"},
+		{context: "arn:aws:eks:eu-west-1:123456789012:cluster/East", want: "arn-aws-eks-eu-west-1-123456789012-cluster-east"},
+		{context: "gke_project_europe-west1_east", want: "gke-project-europe-west1-east"},
+		{context: strings.Repeat("a", 70), want: strings.Repeat("a", 63)},
+		{name: "East_1"},
+		{context: "___"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+tt.context, func(t *testing.T) {
+			got, err := clusterName(tt.name, tt.context)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("expected an error, got %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSharedTrustAnchor(t *testing.T) {
+	_, shared, _ := newTestTrustAnchor(t, "shared.linkerd.cluster.local")
+	_, east, _ := newTestTrustAnchor(t, "east.linkerd.cluster.local")
+	_, west, _ := newTestTrustAnchor(t, "west.linkerd.cluster.local")
+
+	tests := []struct {
+		name         string
+		source       []byte
+		target       []byte
+		sharesAnchor bool
+	}{
+		{name: "same anchor", source: shared, target: shared, sharesAnchor: true},
+		{name: "bundle", source: append(append([]byte{}, east...), shared...), target: append(append([]byte{}, shared...), west...), sharesAnchor: true},
+		{name: "different anchors", source: east, target: west},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, _ := newFakeLinkerd(t)
+			target, _ := newFakeLinkerd(t)
+			for _, c := range []struct {
+				linkerd *Linkerd
+				roots   []byte
+			}{{source, tt.source}, {target, tt.target}} {
+				cm := &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: identityTrustRootsConfigMap},
+					Data:       map[string]string{"ca-bundle.crt": string(c.roots)},
+				}
+				if _, err := c.linkerd.KubeClient.CoreV1().ConfigMaps("linkerd").Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := checkSharedTrustAnchor(source, target, "linkerd")
+			if tt.sharesAnchor && err != nil {
+				t.Error(err)
+			}
+			if !tt.sharesAnchor && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestExportServices(t *testing.T) {
+	linkerd, _ := newFakeLinkerd(t)
+	for _, name := range []string{"books", "authors"} {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": name}}}
+		if _, err := linkerd.KubeClient.CoreV1().Services("bookapp").Create(context.TODO(), svc, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := linkerd.exportServices("bookapp", `{"selector": "app=books"}`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 1 || report[0].Service != "books" || !report[0].Exported {
+		t.Errorf("got report %+v", report)
+	}
+	for name, exported := range map[string]bool{"books": true, "authors": false} {
+		svc, err := linkerd.KubeClient.CoreV1().Services("bookapp").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := svc.Labels[exportedLabel]; ok != exported {
+			t.Errorf("service %s exported: %t, want %t", name, ok, exported)
+		}
+	}
+
+	if _, err = linkerd.exportServices("bookapp", `{"services": ["books"]}`, true); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := linkerd.KubeClient.CoreV1().Services("bookapp").Get(context.TODO(), "books", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := svc.Labels[exportedLabel]; ok {
+		t.Error("service books is still exported")
+	}
+
+	if _, err = linkerd.exportServices("", `{}`, false); err == nil {
+		t.Error("expected an error without a namespace")
+	}
+}
+
+func TestWaitForMirrors(t *testing.T) {
+	east, _ := newFakeLinkerd(t)
+	west, _ := newFakeLinkerd(t)
+	linked := map[string]*Linkerd{"east": east, "west": west}
+	for _, l := range linked {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "books-target"}}
+		if _, err := l.KubeClient.CoreV1().Services("bookapp").Create(context.TODO(), svc, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report := []ServiceMirrorStatus{{Service: "books", Exported: true, Mirrors: map[string]string{}}}
+	waitForMirrors(linked, "bookapp", "target", report, true)
+	for name := range linked {
+		if report[0].Mirrors[name] != mirrorReady {
+			t.Errorf("mirror in %s is %s, want %s", name, report[0].Mirrors[name], mirrorReady)
+		}
+	}
+
+	for _, l := range linked {
+		if err := l.KubeClient.CoreV1().Services("bookapp").Delete(context.TODO(), "books-target", metav1.DeleteOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report = []ServiceMirrorStatus{{Service: "books", Mirrors: map[string]string{}}}
+	waitForMirrors(linked, "bookapp", "target", report, false)
+	for name := range linked {
+		if report[0].Mirrors[name] != mirrorRemoved {
+			t.Errorf("mirror in %s is %s, want %s", name, report[0].Mirrors[name], mirrorRemoved)
+		}
+	}
+}
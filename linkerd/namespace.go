@@ -0,0 +1,252 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const proxyInjectorWebhook = "linkerd-proxy-injector-webhook-config"
+
+// systemNamespaces are never meshed by the annotate-namespace operation
+var systemNamespaces = map[string]bool{
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// AnnotateNamespaceOptions is the body of the annotate-namespace operation
+type AnnotateNamespaceOptions struct {
+	// Selector selects the namespaces by label instead of the namespace of the operation
+	Selector string `json:"selector,omitempty"`
+	// CreateMissing creates the namespace of the operation if it does not exist
+	CreateMissing bool `json:"createMissing,omitempty"`
+	// ControlPlaneNamespace defaults to linkerd, it is never meshed
+	ControlPlaneNamespace string `json:"controlPlaneNamespace,omitempty"`
+	// AllowConflicts marks namespaces even if another mesh injects its sidecar into them
+	AllowConflicts bool `json:"allowConflicts,omitempty"`
+
+	// ApplyNow restarts the workloads of the namespaces so that
+	// the annotation applies to the running pods as well
+	ApplyNow bool `json:"applyNow,omitempty"`
+	// MaxUnavailable is the number or percentage of workloads
+	// restarted at once, defaults to one
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// Timeout for every wave to become ready, defaults to five minutes
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// NamespaceMeshStatus reports how many pods of a namespace are meshed
+type NamespaceMeshStatus struct {
+	Namespace    string   `json:"namespace"`
+	Restarted    int      `json:"restarted"`
+	MeshedPods   int      `json:"meshedPods"`
+	UnmeshedPods int      `json:"unmeshedPods"`
+	Unmeshed     []string `json:"unmeshed,omitempty"`
+}
+
+// annotateNamespace marks the namespaces for proxy injection, or removes the mark, and in
+// apply now mode restarts their workloads in waves. System namespaces are refused and the
+// namespaces have to be in the scope of the proxy injector. The mesh status of the namespaces
+// is returned in apply now mode only, the running pods do not change otherwise
+func (linkerd *Linkerd) annotateNamespace(opID, namespace, body string, del bool) ([]NamespaceMeshStatus, error) {
+	opts := AnnotateNamespaceOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	if opts.ControlPlaneNamespace == "" {
+		opts.ControlPlaneNamespace = defaultControlPlaneNamespace
+	}
+	timeout := defaultRolloutTimeout
+	if opts.Timeout != "" {
+		d, err := time.ParseDuration(opts.Timeout)
+		if err != nil {
+			return nil, ErrParseOperationBody(err)
+		}
+		timeout = d
+	}
+
+	namespaces, missing, err := linkerd.selectNamespaces(namespace, opts.Selector, opts.CreateMissing && !del)
+	if err != nil {
+		return nil, ErrAnnotateNamespace(err)
+	}
+	if !del {
+		if err = checkMeshableNamespaces(namespaces, opts.ControlPlaneNamespace); err != nil {
+			return nil, ErrAnnotateNamespace(err)
+		}
+		if err = linkerd.checkInjectorScope(namespaces); err != nil {
+			return nil, ErrAnnotateNamespace(err)
+		}
+	}
+	// All namespaces are checked before any of them is marked, a missing namespace has no conflicts
+	if !del && !opts.AllowConflicts && !missing {
+		for _, ns := range namespaces {
+			if err = linkerd.checkNamespaceConflicts(ns.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// A missing namespace is only created once it passed the checks
+	if missing {
+		if _, err = linkerd.KubeClient.CoreV1().Namespaces().Create(context.TODO(), &namespaces[0], metav1.CreateOptions{}); err != nil {
+			return nil, ErrAnnotateNamespace(err)
+		}
+	}
+
+	for _, ns := range namespaces {
+		if err = linkerd.LoadNamespaceToMesh(ns.Name, del); err != nil {
+			return nil, err
+		}
+	}
+	if !opts.ApplyNow {
+		return nil, nil
+	}
+
+	reports := make([]NamespaceMeshStatus, 0, len(namespaces))
+	for _, ns := range namespaces {
+		workloads, err := linkerd.listRestartableWorkloads(ns.Name)
+		if err != nil {
+			return nil, ErrRollout(err, ns.Name)
+		}
+		size, err := waveSize(opts.MaxUnavailable, len(workloads))
+		if err != nil {
+			return nil, err
+		}
+		if err = linkerd.restartInWaves(opID, workloads, size, timeout, nil); err != nil {
+			return nil, err
+		}
+
+		report, err := linkerd.namespaceMeshStatus(ns.Name)
+		if err != nil {
+			return nil, ErrRollout(err, ns.Name)
+		}
+		report.Restarted = len(workloads)
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// selectNamespaces returns the namespaces matching the selector, or the named namespace. If
+// it does not exist and create is set, the namespace to create is returned and missing is set
+func (linkerd *Linkerd) selectNamespaces(name, selector string, create bool) ([]corev1.Namespace, bool, error) {
+	client := linkerd.KubeClient.CoreV1().Namespaces()
+	if selector != "" {
+		list, err := client.List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, false, err
+		}
+		if len(list.Items) == 0 {
+			return nil, false, fmt.Errorf("no namespace matches the selector %s", selector)
+		}
+		return list.Items, false, nil
+	}
+
+	ns, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+	if kubeerror.IsNotFound(err) && create {
+		return []corev1.Namespace{{ObjectMeta: metav1.ObjectMeta{Name: name}}}, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return []corev1.Namespace{*ns}, false, nil
+}
+
+// checkMeshableNamespaces refuses the kubernetes system namespaces and those of the control plane
+func checkMeshableNamespaces(namespaces []corev1.Namespace, controlPlane string) error {
+	var refused []string
+	for _, ns := range namespaces {
+		if systemNamespaces[ns.Name] || ns.Name == controlPlane || ns.Labels["linkerd.io/is-control-plane"] == "true" {
+			refused = append(refused, ns.Name)
+		}
+	}
+
+	if len(refused) > 0 {
+		return fmt.Errorf("system namespaces cannot be meshed: %s", strings.Join(refused, ", "))
+	}
+	return nil
+}
+
+// checkInjectorScope verifies that the namespace selector of the proxy injector webhook
+// matches the namespaces, the annotation has no effect on the namespaces it does not match
+func (linkerd *Linkerd) checkInjectorScope(namespaces []corev1.Namespace) error {
+	webhook, err := linkerd.KubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.TODO(), proxyInjectorWebhook, metav1.GetOptions{})
+	if kubeerror.IsNotFound(err) {
+		return fmt.Errorf("the proxy injector webhook %s is not installed", proxyInjectorWebhook)
+	}
+	if err != nil {
+		return err
+	}
+
+	var outside []string
+	for _, ns := range namespaces {
+		matched := false
+		for _, hook := range webhook.Webhooks {
+			if hook.NamespaceSelector == nil {
+				matched = true
+				break
+			}
+			selector, err := metav1.LabelSelectorAsSelector(hook.NamespaceSelector)
+			if err != nil {
+				return err
+			}
+			if selector.Matches(labels.Set(ns.Labels)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			outside = append(outside, ns.Name)
+		}
+	}
+
+	if len(outside) > 0 {
+		return fmt.Errorf("the namespace selector of the proxy injector does not match: %s", strings.Join(outside, ", "))
+	}
+	return nil
+}
+
+// listRestartableWorkloads returns the deployments, statefulsets and daemonsets of the namespace
+func (linkerd *Linkerd) listRestartableWorkloads(namespace string) ([]workload, error) {
+	all, err := linkerd.listNamespaceWorkloads(namespace, "")
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := []workload{}
+	for _, w := range all {
+		if w.Kind == deploymentKind || w.Kind == statefulSetKind || w.Kind == daemonSetKind {
+			workloads = append(workloads, w)
+		}
+	}
+	return workloads, nil
+}
+
+// namespaceMeshStatus counts the running pods of the namespace with and without the linkerd proxy
+func (linkerd *Linkerd) namespaceMeshStatus(namespace string) (*NamespaceMeshStatus, error) {
+	pods, err := linkerd.KubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &NamespaceMeshStatus{Namespace: namespace}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if isMeshed(pod) {
+			report.MeshedPods++
+		} else {
+			report.UnmeshedPods++
+			report.Unmeshed = append(report.Unmeshed, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+		}
+	}
+	return report, nil
+}
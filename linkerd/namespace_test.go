@@ -0,0 +1,116 @@
+package linkerd
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckMeshableNamespaces(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace corev1.Namespace
+		meshable  bool
+	}{
+		{name: "application", namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bookapp"}}, meshable: true},
+		{name: "kube-system", namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}}},
+		{name: "control plane", namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "linkerd"}}},
+		{name: "labeled control plane", namespace: corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "linkerd-edge", Labels: map[string]string{"linkerd.io/is-control-plane": "true"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMeshableNamespaces([]corev1.Namespace{tt.namespace}, "linkerd")
+			if tt.meshable && err != nil {
+				t.Error(err)
+			}
+			if !tt.meshable && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// createInjectorWebhook installs the webhook of the proxy injector, which skips the
+// namespaces labeled with config.linkerd.io/admission-webhooks=disabled
+func createInjectorWebhook(t *testing.T, linkerd *Linkerd) {
+	webhook := &admissionv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: proxyInjectorWebhook},
+		Webhooks: []admissionv1.MutatingWebhook{{
+			Name: "linkerd-proxy-injector.linkerd.io",
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "config.linkerd.io/admission-webhooks", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"disabled"}},
+			}},
+		}},
+	}
+	if _, err := linkerd.KubeClient.AdmissionregistrationV1().MutatingWebhookConfigurations().Create(context.TODO(), webhook, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckInjectorScope(t *testing.T) {
+	inScope := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bookapp"}}
+	outside := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Labels: map[string]string{"config.linkerd.io/admission-webhooks": "disabled"}}}
+
+	t.Run("not installed", func(t *testing.T) {
+		linkerd, _ := newFakeLinkerd(t)
+		if err := linkerd.checkInjectorScope([]corev1.Namespace{inScope}); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	linkerd, _ := newFakeLinkerd(t)
+	createInjectorWebhook(t, linkerd)
+	tests := []struct {
+		name       string
+		namespaces []corev1.Namespace
+		inScope    bool
+	}{
+		{name: "selected", namespaces: []corev1.Namespace{inScope}, inScope: true},
+		{name: "skipped", namespaces: []corev1.Namespace{outside}},
+		{name: "one of them skipped", namespaces: []corev1.Namespace{inScope, outside}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := linkerd.checkInjectorScope(tt.namespaces)
+			if tt.inScope && err != nil {
+				t.Error(err)
+			}
+			if !tt.inScope && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestAnnotateMissingNamespace(t *testing.T) {
+	t.Run("created", func(t *testing.T) {
+		linkerd, cluster := newFakeLinkerd(t)
+		createInjectorWebhook(t, linkerd)
+		if _, err := linkerd.annotateNamespace("", "bookapp", `{"createMissing": true}`, false); err != nil {
+			t.Fatal(err)
+		}
+		ns := cluster.object("v1", "namespaces", "", "bookapp")
+		if ns == nil {
+			t.Fatal("namespace was not created")
+		}
+		annotations, _ := ns["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+		if annotations[injectAnnotation] != injectEnabled {
+			t.Errorf("got annotations %v", annotations)
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		linkerd, cluster := newFakeLinkerd(t)
+		if _, err := linkerd.annotateNamespace("", "bookapp", `{"createMissing": true}`, false); err == nil {
+			t.Fatal("expected an error without the proxy injector")
+		}
+		if cluster.object("v1", "namespaces", "", "bookapp") != nil {
+			t.Error("namespace was created although it failed the checks")
+		}
+	})
+}
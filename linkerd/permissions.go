@@ -0,0 +1,91 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// verifyPermissions checks with a SelfSubjectRulesReview that the credentials in use
+// grant the permissions of the published roles in the given namespace
+func (linkerd *Linkerd) verifyPermissions(namespace string) error {
+	review := &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{
+			Namespace: namespace,
+		},
+	}
+	review, err := linkerd.KubeClient.AuthorizationV1().SelfSubjectRulesReviews().Create(context.TODO(), review, metav1.CreateOptions{})
+	if err != nil {
+		return ErrPermissions(err)
+	}
+
+	missing := map[string][]string{}
+	for _, rule := range internalconfig.RequiredPermissions {
+		for _, group := range rule.APIGroups {
+			for _, resource := range rule.Resources {
+				key := resource
+				if group != "" {
+					key = fmt.Sprintf("%s/%s", group, resource)
+				}
+				for _, verb := range rule.Verbs {
+					if len(rule.ResourceNames) == 0 {
+						if !isAllowed(review.Status.ResourceRules, group, resource, verb, "") {
+							missing[key] = append(missing[key], verb)
+						}
+						continue
+					}
+					for _, name := range rule.ResourceNames {
+						if !isAllowed(review.Status.ResourceRules, group, resource, verb, name) {
+							missing[key] = append(missing[key], fmt.Sprintf("%s %s", verb, name))
+						}
+					}
+				}
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for resource, verbs := range missing {
+		lines = append(lines, fmt.Sprintf("%s (%s)", resource, strings.Join(verbs, ", ")))
+	}
+	sort.Strings(lines)
+	msg := strings.Join(lines, "; ")
+	// Authorizers which cannot list rules, e.g. webhooks, make the review incomplete
+	if review.Status.Incomplete {
+		msg = fmt.Sprintf("%s, the review is incomplete: %s", msg, review.Status.EvaluationError)
+	}
+	return ErrPermissions(fmt.Errorf("%s", msg))
+}
+
+// isAllowed returns true if one of the rules grants the verb on the named object of the
+// resource, or on every object if the name is empty
+func isAllowed(rules []authorizationv1.ResourceRule, group, resource, verb, name string) bool {
+	for _, rule := range rules {
+		// Rules restricted to named objects do not grant the permission in general
+		if len(rule.ResourceNames) > 0 && (name == "" || !matches(rule.ResourceNames, name)) {
+			continue
+		}
+		if matches(rule.APIGroups, group) && matches(rule.Resources, resource) && matches(rule.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the value or a wildcard is in the list
+func matches(list []string, value string) bool {
+	for _, v := range list {
+		if v == value || v == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package linkerd
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	"github.com/layer5io/meshkit/errors"
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+func TestIsAllowed(t *testing.T) {
+	rules := []authorizationv1.ResourceRule{
+		{APIGroups: []string{""}, Resources: []string{"pods", "services"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{"apps"}, Resources: []string{"*"}, Verbs: []string{"*"}},
+		{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterroles"}, Verbs: []string{"bind"}, ResourceNames: []string{"linkerd-heartbeat"}},
+	}
+
+	tests := []struct {
+		group, resource, verb, name string
+		allowed                     bool
+	}{
+		{group: "", resource: "pods", verb: "list", allowed: true},
+		{group: "", resource: "pods", verb: "delete"},
+		{group: "", resource: "secrets", verb: "get"},
+		{group: "apps", resource: "deployments", verb: "patch", allowed: true},
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "bind", name: "linkerd-heartbeat", allowed: true},
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "bind", name: "cluster-admin"},
+		{group: "rbac.authorization.k8s.io", resource: "clusterroles", verb: "bind"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join([]string{tt.group, tt.resource, tt.verb, tt.name}, "/"), func(t *testing.T) {
+			if allowed := isAllowed(rules, tt.group, tt.resource, tt.verb, tt.name); allowed != tt.allowed {
+				t.Errorf("got %t, want %t", allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestVerifyPermissions(t *testing.T) {
+	// granted converts the required permissions into the rules of a review, leaving out secrets
+	granted := func(secrets bool) []authorizationv1.ResourceRule {
+		rules := []authorizationv1.ResourceRule{}
+		for _, r := range internalconfig.RequiredPermissions {
+			if !secrets && len(r.Resources) == 1 && r.Resources[0] == "secrets" {
+				continue
+			}
+			rules = append(rules, authorizationv1.ResourceRule{APIGroups: r.APIGroups, Resources: r.Resources, Verbs: r.Verbs, ResourceNames: r.ResourceNames})
+		}
+		return rules
+	}
+
+	tests := []struct {
+		name    string
+		rules   []authorizationv1.ResourceRule
+		missing string
+	}{
+		{name: "published roles", rules: granted(true)},
+		{name: "cluster role only", rules: granted(false), missing: "secrets (get, list, watch, create, update, patch, delete)"},
+		{name: "cluster admin", rules: []authorizationv1.ResourceRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkerd, cluster := newFakeLinkerd(t)
+			var namespace string
+			cluster.hook(http.MethodPost, "/apis/authorization.k8s.io/v1/selfsubjectrulesreviews", func(w http.ResponseWriter, r *http.Request) {
+				review := authorizationv1.SelfSubjectRulesReview{}
+				if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+					t.Error(err)
+				}
+				namespace = review.Spec.Namespace
+				review.Status.ResourceRules = tt.rules
+				writeJSON(w, http.StatusCreated, review)
+			})
+
+			err := linkerd.verifyPermissions("linkerd")
+			if namespace != "linkerd" {
+				t.Errorf("reviewed namespace %q", namespace)
+			}
+			if tt.missing == "" {
+				if err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(errors.GetSDescription(err), tt.missing) {
+				t.Errorf("got %v, want missing %s", err, tt.missing)
+			}
+		})
+	}
+}
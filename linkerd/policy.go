@@ -0,0 +1,477 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	policyGroup                    = "policy.linkerd.io"
+	defaultInboundPolicyAnnotation = "config.linkerd.io/default-inbound-policy"
+	denyInboundPolicy              = "deny"
+	// policyPreviewWindow is the time range the observed traffic is taken from
+	policyPreviewWindow = "5m"
+	inboundTrafficQuery = `sum(rate(request_total{direction="inbound", namespace="%s"}[%s])) by (pod, client_id, target_addr)`
+)
+
+var serverResource = schema.GroupVersionResource{Group: policyGroup, Version: "v1beta1", Resource: "servers"}
+
+// AuthorizationPolicyOptions is the body of the authorization-policy operation, it allows the
+// clients with one of the identities, or from one of the networks, to reach the port of the workload
+type AuthorizationPolicyOptions struct {
+	// Workload is the name of the workload, optionally prefixed by its kind as in "deployment/web"
+	Workload string             `json:"workload"`
+	Port     intstr.IntOrString `json:"port"`
+	// ProxyProtocol of the port, the proxy detects the protocol if it is empty
+	ProxyProtocol string `json:"proxyProtocol,omitempty"`
+
+	// Identities of the meshed clients, "*" allows any meshed client
+	// and "*.<domain>" the clients whose identity ends with the domain
+	Identities []string `json:"identities,omitempty"`
+	// Networks are the CIDRs of the clients
+	Networks []string `json:"networks,omitempty"`
+
+	// Preview only reports the resources and the traffic they would block
+	Preview bool `json:"preview,omitempty"`
+	// ControlPlaneNamespace defaults to linkerd
+	ControlPlaneNamespace string `json:"controlPlaneNamespace,omitempty"`
+
+	PrometheusOptions `json:",inline"`
+}
+
+// DefaultDenyPolicyOptions is the body of the default-deny-policy operation, it denies
+// the inbound traffic of the namespace which is not authorized explicitly
+type DefaultDenyPolicyOptions struct {
+	// Preview only reports the traffic which would be blocked
+	Preview bool `json:"preview,omitempty"`
+	// ControlPlaneNamespace defaults to linkerd
+	ControlPlaneNamespace string `json:"controlPlaneNamespace,omitempty"`
+
+	PrometheusOptions `json:",inline"`
+}
+
+// PolicyReport lists the generated resources and, for previews, the traffic they block
+type PolicyReport struct {
+	API      string `json:"api"`
+	Manifest string `json:"manifest,omitempty"`
+	Applied  bool   `json:"applied"`
+	// Blocked is the traffic observed recently which the policy denies
+	Blocked []BlockedTraffic `json:"blocked,omitempty"`
+	// Note points out what the preview cannot judge
+	Note string `json:"note,omitempty"`
+}
+
+// BlockedTraffic is the inbound traffic of a pod from a client
+type BlockedTraffic struct {
+	Pod string `json:"pod"`
+	// Client is the identity of the client, empty for unauthenticated clients
+	Client      string  `json:"client"`
+	Port        string  `json:"port"`
+	RequestRate float64 `json:"requestRate"`
+	Reason      string  `json:"reason"`
+}
+
+// applyAuthorizationPolicy generates a Server for the port of the workload and the resources
+// authorizing its clients for the policy API of the installed linkerd release, and applies them
+func (linkerd *Linkerd) applyAuthorizationPolicy(namespace, body string, del bool) (*PolicyReport, error) {
+	opts := AuthorizationPolicyOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	if err := validateAuthorizationPolicy(opts); err != nil {
+		return nil, ErrPolicy(err)
+	}
+	api, err := linkerd.policyAPI(opts.ControlPlaneNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	workloads, err := linkerd.selectWorkloads(namespace, []string{opts.Workload}, "")
+	if err != nil {
+		return nil, ErrPolicy(err)
+	}
+	if len(workloads) != 1 {
+		return nil, ErrPolicy(fmt.Errorf("workload %s is ambiguous, prefix it with its kind", opts.Workload))
+	}
+	w := workloads[0]
+	selector, err := linkerd.workloadSelector(w)
+	if err != nil {
+		return nil, ErrPolicy(err)
+	}
+
+	resources := policyResources(api, w, selector, opts)
+	manifest, err := joinManifests(resources)
+	if err != nil {
+		return nil, ErrPolicy(err)
+	}
+	report := &PolicyReport{API: api.Name, Manifest: manifest}
+
+	if opts.Preview && !del {
+		pods, err := linkerd.workloadPods(w)
+		if err != nil {
+			return nil, ErrPolicy(err)
+		}
+		flows, err := linkerd.inboundTraffic(namespace, opts.PrometheusOptions)
+		if err != nil {
+			return nil, err
+		}
+		report.Blocked = []BlockedTraffic{}
+		for _, f := range flows {
+			pod := findPod(pods, f.Pod)
+			if pod == nil || !portMatches(pod, opts.Port, f.Port) {
+				continue
+			}
+			if reason, blocked := identityBlocked(f.Client, opts.Identities); blocked {
+				f.Reason = reason
+				if len(opts.Networks) > 0 {
+					f.Reason += ", unless the client is within the networks"
+				}
+				report.Blocked = append(report.Blocked, f)
+			}
+		}
+		if len(opts.Networks) > 0 {
+			report.Note = "the metrics carry no client addresses, the preview cannot judge which clients the networks allow"
+		}
+		return report, nil
+	}
+
+	if err = linkerd.applyManifest([]byte(manifest), del, namespace); err != nil {
+		return nil, ErrPolicy(err)
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// applyDefaultDenyPolicy sets the default inbound policy of the namespace to deny, the
+// proxies of the namespace pick it up once their pods are restarted
+func (linkerd *Linkerd) applyDefaultDenyPolicy(namespace, body string, del bool) (*PolicyReport, error) {
+	opts := DefaultDenyPolicyOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	api, err := linkerd.policyAPI(opts.ControlPlaneNamespace)
+	if err != nil {
+		return nil, err
+	}
+	report := &PolicyReport{API: api.Name}
+
+	if opts.Preview && !del {
+		report.Blocked, err = linkerd.previewDefaultDeny(namespace, opts.PrometheusOptions)
+		if err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+
+	var value interface{} = denyInboundPolicy
+	if del {
+		value = nil
+	}
+	if err = linkerd.patchNamespaceAnnotations(namespace, map[string]interface{}{defaultInboundPolicyAnnotation: value}); err != nil {
+		return nil, ErrPolicy(err)
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// previewDefaultDeny returns the inbound traffic of the namespace to pods and ports no Server selects
+func (linkerd *Linkerd) previewDefaultDeny(namespace string, prometheus PrometheusOptions) ([]BlockedTraffic, error) {
+	pods, err := linkerd.KubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, ErrPolicy(err)
+	}
+	servers, err := linkerd.DynamicKubeClient.Resource(serverResource).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil && !kubeerror.IsNotFound(err) {
+		return nil, ErrPolicy(err)
+	}
+	if servers == nil {
+		servers = &unstructured.UnstructuredList{}
+	}
+
+	flows, err := linkerd.inboundTraffic(namespace, prometheus)
+	if err != nil {
+		return nil, err
+	}
+	blocked := []BlockedTraffic{}
+	for _, f := range flows {
+		pod := findPod(pods.Items, f.Pod)
+		if pod == nil {
+			continue
+		}
+		selected := false
+		for _, server := range servers.Items {
+			if serverSelects(server, pod, f.Port) {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			f.Reason = "no server authorizes the port"
+			blocked = append(blocked, f)
+		}
+	}
+	return blocked, nil
+}
+
+// policySummary summarizes the outcome of a policy operation
+func policySummary(report *PolicyReport, del bool) string {
+	switch {
+	case !report.Applied:
+		return fmt.Sprintf("Policy would block %d observed flows", len(report.Blocked))
+	case del:
+		return "Policy removed successfully"
+	}
+	return "Policy applied successfully"
+}
+
+// policyAPI returns the policy API of the installed control plane
+func (linkerd *Linkerd) policyAPI(controlPlaneNamespace string) (internalconfig.PolicyAPI, error) {
+	if controlPlaneNamespace == "" {
+		controlPlaneNamespace = defaultControlPlaneNamespace
+	}
+	version, err := linkerd.getControlPlaneVersion(controlPlaneNamespace)
+	if kubeerror.IsNotFound(err) {
+		return internalconfig.PolicyAPI{}, ErrControlPlaneNotFound(controlPlaneNamespace)
+	}
+	if err != nil {
+		return internalconfig.PolicyAPI{}, ErrPolicy(err)
+	}
+	api, ok := internalconfig.GetPolicyAPI(version)
+	if !ok {
+		return internalconfig.PolicyAPI{}, ErrPolicy(fmt.Errorf("linkerd %s does not support authorization policies", version))
+	}
+	return api, nil
+}
+
+// validateAuthorizationPolicy checks the port and the clients of the policy
+func validateAuthorizationPolicy(opts AuthorizationPolicyOptions) error {
+	if opts.Workload == "" {
+		return fmt.Errorf("no workload given")
+	}
+	if opts.Port.String() == "" || opts.Port.String() == "0" {
+		return fmt.Errorf("no port given")
+	}
+	if len(opts.Identities) == 0 && len(opts.Networks) == 0 {
+		return fmt.Errorf("neither identities nor networks given")
+	}
+	for _, id := range opts.Identities {
+		if id == "" {
+			return fmt.Errorf("empty identity")
+		}
+	}
+	for _, n := range opts.Networks {
+		if _, _, err := net.ParseCIDR(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyResources returns the Server of the port of the workload and the resources
+// authorizing its clients, ServerAuthorizations for linkerd 2.11 and AuthorizationPolicies
+// with their authentications for later releases
+func policyResources(api internalconfig.PolicyAPI, w workload, selector *metav1.LabelSelector, opts AuthorizationPolicyOptions) []map[string]interface{} {
+	name := strings.ToLower(fmt.Sprintf("%s-%s", w.Name, opts.Port.String()))
+	serverSpec := map[string]interface{}{
+		"podSelector": selector,
+		"port":        opts.Port,
+	}
+	if opts.ProxyProtocol != "" {
+		serverSpec["proxyProtocol"] = opts.ProxyProtocol
+	}
+	resources := []map[string]interface{}{
+		policyResource("v1beta1", "Server", name, w.Namespace, serverSpec),
+	}
+
+	networks := make([]map[string]interface{}, 0, len(opts.Networks))
+	for _, n := range opts.Networks {
+		networks = append(networks, map[string]interface{}{"cidr": n})
+	}
+
+	// Every kind of client gets an authorization of its own, a client
+	// is allowed if any of the authorizations of the server allows it
+	if api.Name == internalconfig.ServerAuthorizationAPI {
+		if len(opts.Identities) > 0 {
+			resources = append(resources, policyResource("v1beta1", "ServerAuthorization", name+"-identities", w.Namespace, map[string]interface{}{
+				"server": map[string]interface{}{"name": name},
+				"client": map[string]interface{}{"meshTLS": map[string]interface{}{"identities": opts.Identities}},
+			}))
+		}
+		if len(networks) > 0 {
+			resources = append(resources, policyResource("v1beta1", "ServerAuthorization", name+"-networks", w.Namespace, map[string]interface{}{
+				"server": map[string]interface{}{"name": name},
+				"client": map[string]interface{}{"networks": networks, "unauthenticated": true},
+			}))
+		}
+		return resources
+	}
+
+	targetRef := map[string]interface{}{"group": policyGroup, "kind": "Server", "name": name}
+	if len(opts.Identities) > 0 {
+		resources = append(resources,
+			policyResource("v1alpha1", "MeshTLSAuthentication", name+"-identities", w.Namespace, map[string]interface{}{
+				"identities": opts.Identities,
+			}),
+			policyResource("v1alpha1", "AuthorizationPolicy", name+"-identities", w.Namespace, map[string]interface{}{
+				"targetRef": targetRef,
+				"requiredAuthenticationRefs": []map[string]interface{}{
+					{"group": policyGroup, "kind": "MeshTLSAuthentication", "name": name + "-identities"},
+				},
+			}))
+	}
+	if len(networks) > 0 {
+		resources = append(resources,
+			policyResource("v1alpha1", "NetworkAuthentication", name+"-networks", w.Namespace, map[string]interface{}{
+				"networks": networks,
+			}),
+			policyResource("v1alpha1", "AuthorizationPolicy", name+"-networks", w.Namespace, map[string]interface{}{
+				"targetRef": targetRef,
+				"requiredAuthenticationRefs": []map[string]interface{}{
+					{"group": policyGroup, "kind": "NetworkAuthentication", "name": name + "-networks"},
+				},
+			}))
+	}
+	return resources
+}
+
+func policyResource(version, kind, name, namespace string, spec map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": policyGroup + "/" + version,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"spec": spec,
+	}
+}
+
+// joinManifests renders the resources into a single multi document manifest
+func joinManifests(resources []map[string]interface{}) (string, error) {
+	docs := make([]string, 0, len(resources))
+	for _, r := range resources {
+		out, err := yaml.Marshal(r)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(out))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// inboundTraffic returns the inbound traffic of the pods of the namespace by client and port
+// observed by the proxies, the source addresses of the clients are not part of the metrics
+func (linkerd *Linkerd) inboundTraffic(namespace string, opts PrometheusOptions) ([]BlockedTraffic, error) {
+	prom, err := linkerd.prometheus(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer prom.Close()
+
+	samples, err := prom.query(fmt.Sprintf(inboundTrafficQuery, namespace, policyPreviewWindow))
+	if err != nil {
+		return nil, err
+	}
+	flows := []BlockedTraffic{}
+	for _, s := range samples {
+		if s.Value == 0 {
+			continue
+		}
+		port := s.Metric["target_addr"]
+		if i := strings.LastIndex(port, ":"); i >= 0 {
+			port = port[i+1:]
+		}
+		flows = append(flows, BlockedTraffic{
+			Pod:         s.Metric["pod"],
+			Client:      s.Metric["client_id"],
+			Port:        port,
+			RequestRate: s.Value,
+		})
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		if flows[i].Pod != flows[j].Pod {
+			return flows[i].Pod < flows[j].Pod
+		}
+		return flows[i].Client < flows[j].Client
+	})
+	return flows, nil
+}
+
+// identityBlocked returns why a client is denied by the identities, if it is.
+// Without identities only networks are authorized, which the metrics cannot tell
+func identityBlocked(client string, identities []string) (string, bool) {
+	if len(identities) == 0 {
+		return "", false
+	}
+	if client == "" {
+		return "unauthenticated client", true
+	}
+	for _, id := range identities {
+		if id == "*" || id == client || (strings.HasPrefix(id, "*.") && strings.HasSuffix(client, id[1:])) {
+			return "", false
+		}
+	}
+	return "identity not allowed", true
+}
+
+// findPod returns the pod with the given name, if any
+func findPod(pods []corev1.Pod, name string) *corev1.Pod {
+	for i := range pods {
+		if pods[i].Name == name {
+			return &pods[i]
+		}
+	}
+	return nil
+}
+
+// portMatches returns true if the port number is the port, or the container port named by it
+func portMatches(pod *corev1.Pod, port intstr.IntOrString, number string) bool {
+	if port.Type == intstr.Int {
+		return strconv.Itoa(port.IntValue()) == number
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, p := range c.Ports {
+			if p.Name == port.StrVal && strconv.Itoa(int(p.ContainerPort)) == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// serverSelects returns true if the Server selects the pod and the port
+func serverSelects(server unstructured.Unstructured, pod *corev1.Pod, port string) bool {
+	spec, _, _ := unstructured.NestedMap(server.Object, "spec")
+	podSelector, _, _ := unstructured.NestedMap(spec, "podSelector")
+	ls := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podSelector, ls); err != nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ls)
+	if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+		return false
+	}
+
+	switch p := spec["port"].(type) {
+	case int64:
+		return portMatches(pod, intstr.FromInt(int(p)), port)
+	case string:
+		return portMatches(pod, intstr.FromString(p), port)
+	}
+	return false
+}
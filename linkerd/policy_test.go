@@ -0,0 +1,149 @@
+package linkerd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestPolicyResources(t *testing.T) {
+	w := workload{Kind: deploymentKind, Namespace: "bookapp", Name: "Books"}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "books"}}
+	opts := AuthorizationPolicyOptions{
+		Port:       intstr.FromString("http"),
+		Identities: []string{"*.bookapp.serviceaccount.identity.linkerd.cluster.local"},
+		Networks:   []string{"10.0.0.0/8"},
+	}
+
+	tests := []struct {
+		api       string
+		resources []string
+		// refs are the names of the resources each authorization refers to
+		refs map[string][]string
+	}{
+		{
+			api: internalconfig.ServerAuthorizationAPI,
+			resources: []string{
+				"policy.linkerd.io/v1beta1 Server books-http",
+				"policy.linkerd.io/v1beta1 ServerAuthorization books-http-identities",
+				"policy.linkerd.io/v1beta1 ServerAuthorization books-http-networks",
+			},
+		},
+		{
+			api: internalconfig.AuthorizationPolicyAPI,
+			resources: []string{
+				"policy.linkerd.io/v1beta1 Server books-http",
+				"policy.linkerd.io/v1alpha1 MeshTLSAuthentication books-http-identities",
+				"policy.linkerd.io/v1alpha1 AuthorizationPolicy books-http-identities",
+				"policy.linkerd.io/v1alpha1 NetworkAuthentication books-http-networks",
+				"policy.linkerd.io/v1alpha1 AuthorizationPolicy books-http-networks",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.api, func(t *testing.T) {
+			resources := policyResources(internalconfig.PolicyAPI{Name: tt.api}, w, selector, opts)
+			names := []string{}
+			for _, r := range resources {
+				obj := unstructured.Unstructured{Object: r}
+				names = append(names, obj.GetAPIVersion()+" "+obj.GetKind()+" "+obj.GetName())
+				if obj.GetNamespace() != "bookapp" {
+					t.Errorf("%s is in namespace %q", obj.GetName(), obj.GetNamespace())
+				}
+			}
+			if !reflect.DeepEqual(names, tt.resources) {
+				t.Fatalf("got resources %v, want %v", names, tt.resources)
+			}
+
+			for _, r := range resources[1:] {
+				spec := r["spec"].(map[string]interface{})
+				switch r["kind"] {
+				case "ServerAuthorization":
+					if server := spec["server"].(map[string]interface{}); server["name"] != "books-http" {
+						t.Errorf("%v authorizes server %v", r["metadata"], server["name"])
+					}
+				case "AuthorizationPolicy":
+					if target := spec["targetRef"].(map[string]interface{}); target["name"] != "books-http" || target["kind"] != "Server" {
+						t.Errorf("%v targets %v", r["metadata"], target)
+					}
+					ref := spec["requiredAuthenticationRefs"].([]map[string]interface{})[0]
+					if ref["name"] != r["metadata"].(map[string]interface{})["name"] {
+						t.Errorf("%v requires the authentication %v", r["metadata"], ref["name"])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIdentityBlocked(t *testing.T) {
+	identities := []string{"web.bookapp.serviceaccount.identity.linkerd.cluster.local", "*.emojivoto.serviceaccount.identity.linkerd.cluster.local"}
+
+	tests := []struct {
+		name       string
+		client     string
+		identities []string
+		blocked    bool
+	}{
+		{name: "listed identity", client: "web.bookapp.serviceaccount.identity.linkerd.cluster.local", identities: identities},
+		{name: "domain", client: "voting.emojivoto.serviceaccount.identity.linkerd.cluster.local", identities: identities},
+		{name: "other identity", client: "traffic.bookapp.serviceaccount.identity.linkerd.cluster.local", identities: identities, blocked: true},
+		{name: "unauthenticated", identities: identities, blocked: true},
+		{name: "any meshed client", client: "traffic.bookapp.serviceaccount.identity.linkerd.cluster.local", identities: []string{"*"}},
+		{name: "networks only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, blocked := identityBlocked(tt.client, tt.identities); blocked != tt.blocked {
+				t.Errorf("got %t, want %t", blocked, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestPreviewDefaultDeny(t *testing.T) {
+	linkerd, _ := newFakeLinkerd(t)
+	for _, name := range []string{"books", "authors"} {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": name}}}
+		if _, err := linkerd.KubeClient.CoreV1().Pods("bookapp").Create(context.TODO(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resources := policyResources(internalconfig.PolicyAPI{Name: internalconfig.AuthorizationPolicyAPI},
+		workload{Kind: deploymentKind, Namespace: "bookapp", Name: "books"},
+		&metav1.LabelSelector{MatchLabels: map[string]string{"app": "books"}},
+		AuthorizationPolicyOptions{Port: intstr.FromInt(7002), Identities: []string{"*"}})
+	server := &unstructured.Unstructured{Object: resources[0]}
+	if _, err := linkerd.DynamicKubeClient.Resource(serverResource).Namespace("bookapp").Create(context.TODO(), server, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := "web.bookapp.serviceaccount.identity.linkerd.cluster.local"
+	prom := newFakePrometheus(t, []map[string]string{
+		{"pod": "books", "client_id": client, "target_addr": "10.0.0.1:7002"},
+		{"pod": "books", "client_id": client, "target_addr": "10.0.0.1:9990"},
+		{"pod": "authors", "client_id": client, "target_addr": "10.0.0.2:7001"},
+		{"pod": "gone", "client_id": client, "target_addr": "10.0.0.3:7000"},
+	})
+	blocked, err := linkerd.previewDefaultDeny("bookapp", PrometheusOptions{PrometheusURL: prom.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, b := range blocked {
+		got = append(got, b.Pod+":"+b.Port)
+	}
+	want := []string{"authors:7001", "books:9990"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got blocked flows %v, want %v", got, want)
+	}
+}
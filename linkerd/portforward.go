@@ -0,0 +1,74 @@
+package linkerd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwarder forwards a local port to a port of a pod
+type portForwarder struct {
+	localPort uint16
+	stopCh    chan struct{}
+}
+
+// Address returns the local address the pod port is reachable on
+func (fw *portForwarder) Address() string {
+	return fmt.Sprintf("127.0.0.1:%d", fw.localPort)
+}
+
+// Close stops forwarding
+func (fw *portForwarder) Close() {
+	close(fw.stopCh)
+}
+
+// portForward forwards a random local port to the given port of the pod,
+// the caller is responsible for closing the returned forwarder
+func (linkerd *Linkerd) portForward(namespace, pod string, port int) (*portForwarder, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(&linkerd.RestConfig)
+	if err != nil {
+		return nil, ErrPortForward(err, pod)
+	}
+
+	req := linkerd.KubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	stopCh := make(chan struct{})
+	readyCh := make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return nil, ErrPortForward(err, pod)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fw.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err = <-errCh:
+		if err == nil {
+			err = fmt.Errorf("port forwarding stopped before it was ready")
+		}
+		return nil, ErrPortForward(err, pod)
+	}
+
+	ports, err := fw.GetPorts()
+	if err != nil {
+		close(stopCh)
+		return nil, ErrPortForward(err, pod)
+	}
+
+	return &portForwarder{
+		localPort: ports[0].Local,
+		stopCh:    stopCh,
+	}, nil
+}
@@ -0,0 +1,170 @@
+package linkerd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultPrometheusNamespace = "linkerd-viz"
+	prometheusSelector         = "component=prometheus"
+	prometheusPort             = 9090
+	prometheusTimeout          = 30 * time.Second
+	// minQueryWindow is the shortest range rates are computed over, a rate needs two
+	// samples and the Prometheus of Linkerd scrapes the proxies every ten seconds
+	minQueryWindow = 20 * time.Second
+)
+
+// PrometheusOptions locate the Prometheus scraping the Linkerd proxies
+type PrometheusOptions struct {
+	// PrometheusURL of an external Prometheus, the Prometheus of the viz extension
+	// in PrometheusNamespace is reached through port forwarding if it is empty
+	PrometheusURL string `json:"prometheusURL,omitempty"`
+	// PrometheusNamespace defaults to linkerd-viz, or linkerd for releases before 2.10
+	PrometheusNamespace string `json:"prometheusNamespace,omitempty"`
+}
+
+// prometheusClient runs instant queries against the Prometheus HTTP API
+type prometheusClient struct {
+	url       string
+	forwarder *portForwarder
+	client    *http.Client
+}
+
+// prometheusQueryResponse is the response of the Prometheus instant query API for vector results
+type prometheusQueryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]interface{}    `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// prometheusSample is a single sample of a query result
+type prometheusSample struct {
+	Metric map[string]string
+	Value  float64
+}
+
+// parseQueryWindow parses the range of the rate queries, it defaults to def if the value is empty
+func parseQueryWindow(value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, ErrParseOperationBody(err)
+	}
+	if d < minQueryWindow {
+		return 0, ErrParseOperationBody(fmt.Errorf("window %s is shorter than %s, twice the scrape interval of Prometheus", value, minQueryWindow))
+	}
+	return d, nil
+}
+
+// prometheus returns a client of the Prometheus, the caller is responsible for closing it.
+// The adapter addresses the Prometheus service directly when it runs inside the targeted
+// cluster and port-forwards to a Prometheus pod otherwise. Without a namespace the
+// Prometheus of the viz extension is used, or the one of a pre 2.10 control plane
+func (linkerd *Linkerd) prometheus(opts PrometheusOptions) (*prometheusClient, error) {
+	client := &http.Client{Timeout: prometheusTimeout}
+	if opts.PrometheusURL != "" {
+		return &prometheusClient{url: opts.PrometheusURL, client: client}, nil
+	}
+	namespaces := []string{opts.PrometheusNamespace}
+	if opts.PrometheusNamespace == "" {
+		namespaces = []string{defaultPrometheusNamespace, defaultControlPlaneNamespace}
+	}
+	inCluster := linkerd.contextName == "" && linkerd.kubeconfigSource().Name == internalconfig.KubeconfigSourceInCluster
+
+	for _, ns := range namespaces {
+		if inCluster {
+			services, err := linkerd.KubeClient.CoreV1().Services(ns).List(context.TODO(), metav1.ListOptions{LabelSelector: prometheusSelector})
+			if err != nil {
+				return nil, ErrPrometheus(err)
+			}
+			if len(services.Items) > 0 {
+				address := fmt.Sprintf("http://%s.%s.svc:%d", services.Items[0].Name, ns, prometheusPort)
+				return &prometheusClient{url: address, client: client}, nil
+			}
+			continue
+		}
+
+		pods, err := linkerd.KubeClient.CoreV1().Pods(ns).List(context.TODO(), metav1.ListOptions{LabelSelector: prometheusSelector})
+		if err != nil {
+			return nil, ErrPrometheus(err)
+		}
+		for _, pod := range pods.Items {
+			if pod.Status.Phase != corev1.PodRunning {
+				continue
+			}
+			fw, err := linkerd.portForward(pod.Namespace, pod.Name, prometheusPort)
+			if err != nil {
+				return nil, err
+			}
+			return &prometheusClient{url: "http://" + fw.Address(), forwarder: fw, client: client}, nil
+		}
+	}
+
+	return nil, ErrPrometheus(fmt.Errorf("no running Prometheus found in namespaces %s", strings.Join(namespaces, ", ")))
+}
+
+// Close stops port forwarding to the Prometheus, if any
+func (p *prometheusClient) Close() {
+	if p.forwarder != nil {
+		p.forwarder.Close()
+	}
+}
+
+// query runs an instant query and returns its samples, samples without a value are dropped
+func (p *prometheusClient) query(q string) ([]prometheusSample, error) {
+	resp, err := p.client.Get(fmt.Sprintf("%s/api/v1/query?query=%s", p.url, url.QueryEscape(q)))
+	if err != nil {
+		return nil, ErrPrometheus(err)
+	}
+	defer resp.Body.Close()
+
+	result := prometheusQueryResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, ErrPrometheus(err)
+	}
+	if result.Status != "success" {
+		return nil, ErrPrometheus(fmt.Errorf("query %s failed: %s", q, result.Error))
+	}
+
+	samples := []prometheusSample{}
+	for _, r := range result.Data.Result {
+		s, ok := r.Value[1].(string)
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(v) {
+			continue
+		}
+		samples = append(samples, prometheusSample{Metric: r.Metric, Value: v})
+	}
+	return samples, nil
+}
+
+// scalar runs a query expected to return a single sample, it returns false if there is none
+func (p *prometheusClient) scalar(q string) (float64, bool, error) {
+	samples, err := p.query(q)
+	if err != nil || len(samples) == 0 {
+		return 0, false, err
+	}
+	return samples[0].Value, true, nil
+}
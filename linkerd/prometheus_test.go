@@ -0,0 +1,74 @@
+package linkerd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakePrometheus answers every instant query with a sample per metric, the value of the
+// samples tells the queries apart unless a value is set for a fragment of the query.
+// The queries it received are recorded
+type fakePrometheus struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	queries []string
+	values  map[string]string
+}
+
+func newFakePrometheus(t *testing.T, metrics []map[string]string) *fakePrometheus {
+	fake := &fakePrometheus{values: map[string]string{}}
+	fake.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query().Get("query")
+		value, fragment := "5", ""
+		fake.mu.Lock()
+		fake.queries = append(fake.queries, q)
+		for f, v := range fake.values {
+			if strings.Contains(q, f) && len(f) > len(fragment) {
+				fragment, value = f, v
+			}
+		}
+		fake.mu.Unlock()
+
+		switch {
+		case fragment != "":
+		case strings.HasPrefix(q, "histogram_quantile(0.5,"):
+			value = "10"
+		case strings.HasPrefix(q, "histogram_quantile(0.95,"):
+			value = "20"
+		case strings.HasPrefix(q, "histogram_quantile(0.99,"):
+			value = "30"
+		case strings.Contains(q, `classification="success"`):
+			value = "0.9"
+		}
+
+		result := []interface{}{}
+		for _, m := range metrics {
+			result = append(result, map[string]interface{}{"metric": m, "value": []interface{}{1, value}})
+		}
+		err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   map[string]interface{}{"resultType": "vector", "result": result},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+// set answers the queries containing the fragment with value, the longest fragment wins
+func (p *fakePrometheus) set(fragment, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[fragment] = value
+}
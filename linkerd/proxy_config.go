@@ -0,0 +1,186 @@
+package linkerd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	internalconfig "github.com/layer5io/meshery-linkerd/internal/config"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ProxyConfigOptions is the body of the proxy-config operation. The settings are applied
+// to the namespace, which affects the pods created afterwards, unless workloads are
+// selected by name or label selector, whose pod templates are annotated then
+type ProxyConfigOptions struct {
+	Workloads []string `json:"workloads,omitempty"`
+	Selector  string   `json:"selector,omitempty"`
+
+	// Settings map the names of the config.linkerd.io annotations, e.g. proxy-cpu-limit,
+	// to their values. A delete operation removes the named settings, or all of them
+	// if none are named
+	Settings map[string]string `json:"settings,omitempty"`
+
+	// ControlPlaneNamespace defaults to linkerd, the settings are validated
+	// against the version of the control plane
+	ControlPlaneNamespace string `json:"controlPlaneNamespace,omitempty"`
+}
+
+// ProxyConfigStatus is the effective proxy configuration of a namespace or workload,
+// the settings of a workload take precedence over those of its namespace
+type ProxyConfigStatus struct {
+	Target    string            `json:"target"`
+	Effective map[string]string `json:"effective"`
+	// Message explains why the settings were not applied to the target
+	Message string `json:"message,omitempty"`
+}
+
+// configureProxies validates the proxy settings, sets or removes the annotations on the
+// targets and returns their effective configuration
+func (linkerd *Linkerd) configureProxies(namespace, body string, del bool) ([]ProxyConfigStatus, error) {
+	opts := ProxyConfigOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	if opts.ControlPlaneNamespace == "" {
+		opts.ControlPlaneNamespace = defaultControlPlaneNamespace
+	}
+	version, err := linkerd.getControlPlaneVersion(opts.ControlPlaneNamespace)
+	if kubeerror.IsNotFound(err) {
+		return nil, ErrControlPlaneNotFound(opts.ControlPlaneNamespace)
+	}
+	if err != nil {
+		return nil, ErrProxyConfig(err)
+	}
+
+	annotations, err := proxyConfigAnnotations(opts.Settings, version, del)
+	if err != nil {
+		return nil, ErrProxyConfig(err)
+	}
+
+	if len(opts.Workloads) == 0 && opts.Selector == "" {
+		if err = linkerd.patchNamespaceAnnotations(namespace, annotations); err != nil {
+			return nil, ErrProxyConfig(err)
+		}
+		effective, err := linkerd.effectiveProxyConfig(namespace, nil)
+		if err != nil {
+			return nil, ErrProxyConfig(err)
+		}
+		return []ProxyConfigStatus{{Target: fmt.Sprintf("Namespace %s", namespace), Effective: effective}}, nil
+	}
+
+	workloads, err := linkerd.selectWorkloads(namespace, opts.Workloads, opts.Selector)
+	if err != nil {
+		return nil, ErrProxyConfig(err)
+	}
+	report := make([]ProxyConfigStatus, 0, len(workloads))
+	for _, w := range workloads {
+		// The pod template of a job is immutable, its pods keep the settings they were created with
+		message := ""
+		if w.Kind == jobKind {
+			message = "not configured, the pod template of a job is immutable"
+		} else if err = linkerd.patchPodTemplateAnnotations(w, annotations); err != nil {
+			return nil, ErrProxyConfig(fmt.Errorf("%s: %s", w, err))
+		}
+		effective, err := linkerd.effectiveProxyConfig(namespace, &w)
+		if err != nil {
+			return nil, ErrProxyConfig(err)
+		}
+		report = append(report, ProxyConfigStatus{Target: w.String(), Effective: effective, Message: message})
+	}
+	return report, nil
+}
+
+// proxyConfigAnnotations validates the settings against the schema of the control plane
+// version and turns them into an annotation patch, removals have a nil value
+func proxyConfigAnnotations(settings map[string]string, version string, del bool) (map[string]interface{}, error) {
+	annotations := map[string]interface{}{}
+	if del && len(settings) == 0 {
+		for _, s := range internalconfig.ProxySettings {
+			annotations[s.Annotation()] = nil
+		}
+		return annotations, nil
+	}
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("no settings given")
+	}
+	// The settings supported by a release cannot be told for other versions, e.g. custom image tags
+	if !del && !internalconfig.IsReleaseVersion(version) {
+		return nil, fmt.Errorf("unknown linkerd version %q, the settings are only validated for stable and edge releases", version)
+	}
+
+	var invalid []string
+	for name, value := range settings {
+		s, ok := internalconfig.GetProxySetting(name)
+		if !ok {
+			invalid = append(invalid, fmt.Sprintf("unknown setting %s", name))
+			continue
+		}
+		if del {
+			annotations[s.Annotation()] = nil
+			continue
+		}
+		if !s.Supports(version) {
+			invalid = append(invalid, fmt.Sprintf("%s is not supported by Linkerd %s", name, version))
+			continue
+		}
+		if err := s.Validate(value); err != nil {
+			invalid = append(invalid, err.Error())
+			continue
+		}
+		annotations[s.Annotation()] = value
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return nil, fmt.Errorf("%s", strings.Join(invalid, "; "))
+	}
+	return annotations, nil
+}
+
+// patchNamespaceAnnotations merges the annotations into the namespace, annotations with a nil value are removed
+func (linkerd *Linkerd) patchNamespaceAnnotations(namespace string, annotations map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = linkerd.KubeClient.CoreV1().Namespaces().Patch(context.TODO(), namespace, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// effectiveProxyConfig returns the proxy settings of the namespace overridden by those of the workload, if any
+func (linkerd *Linkerd) effectiveProxyConfig(namespace string, w *workload) (map[string]string, error) {
+	ns, err := linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	effective := map[string]string{}
+	for k, v := range ns.Annotations {
+		if strings.HasPrefix(k, internalconfig.ProxyConfigPrefix) {
+			effective[k] = v
+		}
+	}
+	if w == nil {
+		return effective, nil
+	}
+
+	template, err := linkerd.podTemplate(*w)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range template.Annotations {
+		if strings.HasPrefix(k, internalconfig.ProxyConfigPrefix) {
+			effective[k] = v
+		}
+	}
+	return effective, nil
+}
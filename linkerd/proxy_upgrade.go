@@ -0,0 +1,182 @@
+package linkerd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const proxyUpgradeCheckpoint = "proxy-upgrade"
+
+// UpgradeProxiesOptions is the body of the upgrade-proxies operation
+type UpgradeProxiesOptions struct {
+	// NamespacePriority lists the namespaces whose workloads are upgraded first,
+	// in that order. The workloads of the other namespaces follow
+	NamespacePriority []string `json:"namespacePriority,omitempty"`
+	// MaxUnavailable is the number or percentage of workloads
+	// restarted at once, defaults to one
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// Timeout for every wave to become healthy, defaults to five minutes
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// proxyUpgrade is the persisted progress of a data plane upgrade
+type proxyUpgrade struct {
+	// Version is the control plane version the proxies are upgraded to
+	Version   string   `json:"version"`
+	Completed []string `json:"completed"`
+	// Paused is the reason the last wave failed, if it did
+	Paused    string `json:"paused,omitempty"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// ProxyUpgradeReport lists the workloads whose proxies were upgraded
+type ProxyUpgradeReport struct {
+	Version  string   `json:"version"`
+	Upgraded []string `json:"upgraded"`
+}
+
+// upgradeProxies restarts the meshed workloads running a proxy of another version than the
+// control plane in waves, ordered by namespace priority. Every wave has to be healthy and run
+// the new proxy before the next one starts, otherwise the upgrade pauses. The progress is
+// persisted after every wave so that a paused upgrade resumes where it stopped when the
+// operation is requested again. A delete operation abandons the persisted progress
+func (linkerd *Linkerd) upgradeProxies(opID, namespace, body string, del bool) (*ProxyUpgradeReport, error) {
+	if namespace == "" {
+		namespace = defaultControlPlaneNamespace
+	}
+	if del {
+		return nil, linkerd.clearCheckpoint(namespace, proxyUpgradeCheckpoint)
+	}
+
+	opts := UpgradeProxiesOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	timeout := defaultRolloutTimeout
+	if opts.Timeout != "" {
+		d, err := time.ParseDuration(opts.Timeout)
+		if err != nil {
+			return nil, ErrParseOperationBody(err)
+		}
+		timeout = d
+	}
+
+	version, err := linkerd.getControlPlaneVersion(namespace)
+	if kubeerror.IsNotFound(err) {
+		return nil, ErrControlPlaneNotFound(namespace)
+	}
+	if err != nil {
+		return nil, ErrProxyUpgrade(err)
+	}
+
+	state := &proxyUpgrade{}
+	found, err := linkerd.loadCheckpoint(namespace, proxyUpgradeCheckpoint, state)
+	if err != nil {
+		return nil, err
+	}
+	// Progress towards another version is of no use after the control plane changed again
+	if found && state.Version != version {
+		found = false
+		state = &proxyUpgrade{}
+	}
+	if found {
+		linkerd.streamProgress(opID, "Resuming data plane upgrade", fmt.Sprintf("%d workloads already upgraded, paused at: %s", len(state.Completed), state.Paused))
+	}
+	state.Version = version
+
+	stale, err := linkerd.listWorkloads("", func(pod *corev1.Pod) bool {
+		proxy := proxyContainer(pod)
+		return pod.Namespace != namespace && proxy != nil && imageTag(proxy.Image) != version
+	})
+	if err != nil {
+		return nil, ErrProxyUpgrade(err)
+	}
+	workloads := []workload{}
+	for _, w := range stale {
+		if !contains(state.Completed, w.String()) {
+			workloads = append(workloads, w)
+		}
+	}
+	sortByNamespacePriority(workloads, opts.NamespacePriority)
+
+	size, err := waveSize(opts.MaxUnavailable, len(workloads))
+	if err != nil {
+		return nil, err
+	}
+	linkerd.streamProgress(opID, fmt.Sprintf("Upgrading the proxies of %d workloads to %s", len(workloads), version), "")
+
+	report := &ProxyUpgradeReport{Version: version, Upgraded: []string{}}
+	err = linkerd.restartInWaves(opID, workloads, size, timeout, func(wave []workload) error {
+		names := make([]string, 0, len(wave))
+		for _, w := range wave {
+			names = append(names, w.String())
+		}
+
+		if err := linkerd.checkProxyVersion(wave, version); err != nil {
+			return err
+		}
+
+		state.Paused = ""
+		state.Completed = append(state.Completed, names...)
+		state.UpdatedAt = time.Now().Format(time.RFC3339)
+		report.Upgraded = append(report.Upgraded, names...)
+		return linkerd.saveCheckpoint(namespace, proxyUpgradeCheckpoint, state)
+	})
+	if err != nil {
+		state.Paused = err.Error()
+		state.UpdatedAt = time.Now().Format(time.RFC3339)
+		if serr := linkerd.saveCheckpoint(namespace, proxyUpgradeCheckpoint, state); serr != nil {
+			return nil, serr
+		}
+		return nil, ErrProxyUpgrade(fmt.Errorf("upgrade paused, fix the failing workloads and run the operation again to resume: %s", err))
+	}
+
+	if err = linkerd.clearCheckpoint(namespace, proxyUpgradeCheckpoint); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// checkProxyVersion verifies that all the pods of the workloads run the proxy of the given version
+func (linkerd *Linkerd) checkProxyVersion(workloads []workload, version string) error {
+	for _, w := range workloads {
+		pods, err := linkerd.workloadPods(w)
+		if err != nil {
+			return err
+		}
+		for i := range pods {
+			proxy := proxyContainer(&pods[i])
+			if proxy == nil {
+				return fmt.Errorf("pod %s/%s of %s has no linkerd proxy", pods[i].Namespace, pods[i].Name, w)
+			}
+			if tag := imageTag(proxy.Image); tag != version {
+				return fmt.Errorf("pod %s/%s of %s runs proxy %s", pods[i].Namespace, pods[i].Name, w, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// sortByNamespacePriority orders the workloads of the prioritized namespaces first,
+// in the order of the namespaces, the order is kept otherwise
+func sortByNamespacePriority(workloads []workload, priority []string) {
+	rank := map[string]int{}
+	for i, ns := range priority {
+		rank[ns] = i
+	}
+	position := func(w workload) int {
+		if r, ok := rank[w.Namespace]; ok {
+			return r
+		}
+		return len(priority)
+	}
+
+	sort.SliceStable(workloads, func(i, j int) bool {
+		return position(workloads[i]) < position(workloads[j])
+	})
+}
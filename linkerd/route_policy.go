@@ -0,0 +1,238 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+var serviceProfileResource = schema.GroupVersionResource{Group: "linkerd.io", Version: "v1alpha2", Resource: "serviceprofiles"}
+
+// RoutePolicyOptions is the body of the route-policy operation. A delete
+// operation removes the policies set in the body from the profile
+type RoutePolicyOptions struct {
+	Service string `json:"service"`
+	// ClusterDomain defaults to cluster.local
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+
+	Routes      []RoutePolicy `json:"routes,omitempty"`
+	RetryBudget *RetryBudget  `json:"retryBudget,omitempty"`
+}
+
+// RoutePolicy selects routes of the profile by name or path regex and sets their policies
+type RoutePolicy struct {
+	Name      string `json:"name,omitempty"`
+	PathRegex string `json:"pathRegex,omitempty"`
+
+	IsRetryable *bool  `json:"isRetryable,omitempty"`
+	Timeout     string `json:"timeout,omitempty"`
+}
+
+// RetryBudget limits the retries of the retryable routes of the profile
+type RetryBudget struct {
+	RetryRatio          float64 `json:"retryRatio"`
+	MinRetriesPerSecond int64   `json:"minRetriesPerSecond"`
+	TTL                 string  `json:"ttl"`
+}
+
+// applyRoutePolicy patches the retry and timeout policies of the routes of the service
+// profile of the service and returns a diff of the profile spec before and after
+func (linkerd *Linkerd) applyRoutePolicy(namespace, body string, del bool) (string, error) {
+	opts := RoutePolicyOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return "", err
+	}
+	if opts.Service == "" {
+		return "", ErrRoutePolicy(fmt.Errorf("no service given"))
+	}
+	if opts.ClusterDomain == "" {
+		opts.ClusterDomain = defaultClusterDomain
+	}
+	if err := validateRoutePolicy(opts, del); err != nil {
+		return "", ErrRoutePolicy(err)
+	}
+
+	client := linkerd.DynamicKubeClient.Resource(serviceProfileResource).Namespace(namespace)
+	profile, err := client.Get(context.TODO(), profileName(opts.Service, namespace, opts.ClusterDomain), metav1.GetOptions{})
+	if err != nil {
+		return "", ErrRoutePolicy(err)
+	}
+	before, err := profileSpecYAML(profile)
+	if err != nil {
+		return "", ErrRoutePolicy(err)
+	}
+
+	routes, _, err := unstructured.NestedSlice(profile.Object, "spec", "routes")
+	if err != nil {
+		return "", ErrRoutePolicy(err)
+	}
+	for _, policy := range opts.Routes {
+		matched := 0
+		for _, r := range routes {
+			route, ok := r.(map[string]interface{})
+			if !ok || !policy.matches(route) {
+				continue
+			}
+			matched++
+			policy.apply(route, del)
+		}
+		if matched == 0 {
+			return "", ErrRoutePolicy(fmt.Errorf("no route of the profile matches %s", policy))
+		}
+	}
+	if len(opts.Routes) > 0 {
+		if err = unstructured.SetNestedSlice(profile.Object, routes, "spec", "routes"); err != nil {
+			return "", ErrRoutePolicy(err)
+		}
+	}
+
+	if opts.RetryBudget != nil {
+		if del {
+			unstructured.RemoveNestedField(profile.Object, "spec", "retryBudget")
+		} else {
+			budget := map[string]interface{}{
+				"retryRatio":          opts.RetryBudget.RetryRatio,
+				"minRetriesPerSecond": opts.RetryBudget.MinRetriesPerSecond,
+				"ttl":                 opts.RetryBudget.TTL,
+			}
+			if err = unstructured.SetNestedField(profile.Object, budget, "spec", "retryBudget"); err != nil {
+				return "", ErrRoutePolicy(err)
+			}
+		}
+	}
+
+	updated, err := client.Update(context.TODO(), profile, metav1.UpdateOptions{})
+	if err != nil {
+		return "", ErrRoutePolicy(err)
+	}
+	after, err := profileSpecYAML(updated)
+	if err != nil {
+		return "", ErrRoutePolicy(err)
+	}
+
+	return lineDiff(before, after), nil
+}
+
+// validateRoutePolicy checks the route selectors and the values of the policies,
+// the values are ignored when the policies are removed
+func validateRoutePolicy(opts RoutePolicyOptions, del bool) error {
+	if len(opts.Routes) == 0 && opts.RetryBudget == nil {
+		return fmt.Errorf("neither routes nor a retry budget given")
+	}
+
+	for _, policy := range opts.Routes {
+		if policy.Name == "" && policy.PathRegex == "" {
+			return fmt.Errorf("routes have to be selected by name or path regex")
+		}
+		if policy.Timeout != "" && !del {
+			if _, err := time.ParseDuration(policy.Timeout); err != nil {
+				return fmt.Errorf("timeout of %s: %s", policy, err)
+			}
+		}
+	}
+
+	if b := opts.RetryBudget; b != nil && !del {
+		if b.RetryRatio < 0 || b.MinRetriesPerSecond < 0 {
+			return fmt.Errorf("retry budget must not be negative")
+		}
+		if _, err := time.ParseDuration(b.TTL); err != nil {
+			return fmt.Errorf("ttl of retry budget: %s", err)
+		}
+	}
+	return nil
+}
+
+func (p RoutePolicy) String() string {
+	if p.Name != "" {
+		return fmt.Sprintf("route %q", p.Name)
+	}
+	return fmt.Sprintf("path regex %q", p.PathRegex)
+}
+
+// matches returns true if the route has the name or the path regex of the policy
+func (p RoutePolicy) matches(route map[string]interface{}) bool {
+	if p.Name != "" {
+		name, _, _ := unstructured.NestedString(route, "name")
+		return name == p.Name
+	}
+	pathRegex, _, _ := unstructured.NestedString(route, "condition", "pathRegex")
+	return pathRegex == p.PathRegex
+}
+
+// apply sets the policies on the route, or removes them if del is set
+func (p RoutePolicy) apply(route map[string]interface{}, del bool) {
+	if p.IsRetryable != nil {
+		if del {
+			delete(route, "isRetryable")
+		} else {
+			route["isRetryable"] = *p.IsRetryable
+		}
+	}
+	if p.Timeout != "" {
+		if del {
+			delete(route, "timeout")
+		} else {
+			route["timeout"] = p.Timeout
+		}
+	}
+}
+
+// profileSpecYAML renders the spec of the service profile
+func profileSpecYAML(profile *unstructured.Unstructured) (string, error) {
+	spec, _, err := unstructured.NestedMap(profile.Object, "spec")
+	if err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// lineDiff returns a unified style diff of the lines of two texts
+func lineDiff(before, after string) string {
+	a := strings.Split(strings.TrimSuffix(before, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(after, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			diff.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	return diff.String()
+}
@@ -0,0 +1,45 @@
+package linkerd
+
+import "testing"
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		diff   string
+	}{
+		{
+			name:   "unchanged",
+			before: "a\nb\n",
+			after:  "a\nb\n",
+			diff:   "  a\n  b\n",
+		},
+		{
+			name:   "added line",
+			before: "routes:\n- name: GET /books\n",
+			after:  "routes:\n- name: GET /books\n  isRetryable: true\n",
+			diff:   "  routes:\n  - name: GET /books\n+   isRetryable: true\n",
+		},
+		{
+			name:   "removed line",
+			before: "a\nb\nc\n",
+			after:  "a\nc\n",
+			diff:   "  a\n- b\n  c\n",
+		},
+		{
+			name:   "changed line",
+			before: "timeout: 1s\nttl: 10s\n",
+			after:  "timeout: 2s\nttl: 10s\n",
+			diff:   "- timeout: 1s\n+ timeout: 2s\n  ttl: 10s\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := lineDiff(tt.before, tt.after); diff != tt.diff {
+				t.Errorf("got diff\n%s\nwant\n%s", diff, tt.diff)
+			}
+		})
+	}
+}
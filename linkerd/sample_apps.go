@@ -28,31 +28,19 @@ func (linkerd *Linkerd) installSampleApp(namespace string, del bool, templates [
 	return status.Installed, nil
 }
 
-// LoadToMesh adds annotation to service
-func (linkerd *Linkerd) LoadToMesh(namespace string, service string, remove bool) error {
-	deploy, err := linkerd.KubeClient.AppsV1().Deployments(namespace).Get(context.TODO(), service, metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-
-	if deploy.ObjectMeta.Annotations == nil {
-		deploy.ObjectMeta.Annotations = map[string]string{}
-	}
-	deploy.ObjectMeta.Annotations["linkerd.io/inject"] = "enabled"
-
+// LoadToMesh enables proxy injection in the pod template of the deployment, or disables it.
+// Unless allowConflicts is set injection is refused if another mesh injects the deployment
+func (linkerd *Linkerd) LoadToMesh(namespace string, service string, remove, allowConflicts bool) error {
+	value := injectEnabled
 	if remove {
-		delete(deploy.ObjectMeta.Annotations, "linkerd.io/inject")
+		value = injectDisabled
 	}
 
-	_, err = linkerd.KubeClient.AppsV1().Deployments(namespace).Update(context.TODO(), deploy, metav1.UpdateOptions{})
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return linkerd.meshWorkload(workload{Kind: deploymentKind, Namespace: namespace, Name: service}, value, allowConflicts)
 }
 
-// LoadNamespaceToMesh is used to mark namespaces for automatic sidecar injection (or not)
+// LoadNamespaceToMesh is used to mark namespaces for automatic sidecar injection (or not),
+// the callers check for conflicts with other meshes beforehand
 func (linkerd *Linkerd) LoadNamespaceToMesh(namespace string, remove bool) error {
 	ns, err := linkerd.KubeClient.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
 	if err != nil {
@@ -0,0 +1,277 @@
+package linkerd
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	serviceProfileAPIVersion = "linkerd.io/v1alpha2"
+	serviceProfileKind       = "ServiceProfile"
+	defaultClusterDomain     = "cluster.local"
+
+	// Extensions of OpenAPI operations honored by "linkerd profile --open-api" as well
+	retryableExtension = "x-linkerd-retryable"
+	timeoutExtension   = "x-linkerd-timeout"
+)
+
+// httpMethods are the operations of an OpenAPI path item
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+var (
+	pathParam      = regexp.MustCompile(`^\{[^/{}]+\}$`)
+	protoComment   = regexp.MustCompile(`(?s)//[^\n]*|/\*.*?\*/`)
+	protoPackage   = regexp.MustCompile(`\bpackage\s+([\w.]+)\s*;`)
+	protoService   = regexp.MustCompile(`\bservice\s+(\w+)\s*\{`)
+	protoRPCMethod = regexp.MustCompile(`\brpc\s+(\w+)\s*\(`)
+)
+
+// ServiceProfileOptions is the body of the service-profile operation, exactly one
+// of OpenAPI and Proto has to be set unless the profile is deleted
+type ServiceProfileOptions struct {
+	Service string `json:"service"`
+	// OpenAPI is a Swagger 2.0 or OpenAPI 3 document in YAML or JSON
+	OpenAPI string `json:"openapi,omitempty"`
+	// Proto is a protobuf file declaring gRPC services
+	Proto string `json:"proto,omitempty"`
+	// ClusterDomain defaults to cluster.local
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+}
+
+// serviceProfile is a linkerd.io/v1alpha2 ServiceProfile
+type serviceProfile struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   profileMetadata    `json:"metadata"`
+	Spec       serviceProfileSpec `json:"spec"`
+}
+
+type profileMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type serviceProfileSpec struct {
+	Routes []profileRoute `json:"routes,omitempty"`
+}
+
+type profileRoute struct {
+	Name        string       `json:"name"`
+	Condition   requestMatch `json:"condition"`
+	IsRetryable bool         `json:"isRetryable,omitempty"`
+	Timeout     string       `json:"timeout,omitempty"`
+}
+
+type requestMatch struct {
+	Method    string `json:"method,omitempty"`
+	PathRegex string `json:"pathRegex,omitempty"`
+}
+
+// openAPIDocument holds the parts of Swagger 2.0 and OpenAPI 3 documents routes are generated from
+type openAPIDocument struct {
+	Swagger  string `json:"swagger,omitempty"`
+	OpenAPI  string `json:"openapi,omitempty"`
+	BasePath string `json:"basePath,omitempty"`
+	Servers  []struct {
+		URL string `json:"url"`
+	} `json:"servers,omitempty"`
+	Paths map[string]map[string]interface{} `json:"paths"`
+}
+
+// applyServiceProfile generates the ServiceProfile of the service from the OpenAPI document
+// or the protobuf file, applies it or deletes it, and returns the generated YAML
+func (linkerd *Linkerd) applyServiceProfile(namespace, body string, del bool) (string, string, error) {
+	st := status.Deploying
+	if del {
+		st = status.Removing
+	}
+
+	opts := ServiceProfileOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return st, "", err
+	}
+	if namespace == "" {
+		return st, "", ErrServiceProfile(fmt.Errorf("no namespace given"))
+	}
+	if opts.Service == "" {
+		return st, "", ErrServiceProfile(fmt.Errorf("no service given"))
+	}
+	if opts.ClusterDomain == "" {
+		opts.ClusterDomain = defaultClusterDomain
+	}
+
+	var routes []profileRoute
+	var err error
+	switch {
+	case del:
+	case opts.OpenAPI != "" && opts.Proto != "":
+		err = fmt.Errorf("either an OpenAPI document or a proto file has to be given, not both")
+	case opts.OpenAPI != "":
+		routes, err = openAPIRoutes(opts.OpenAPI)
+	case opts.Proto != "":
+		routes, err = protoRoutes(opts.Proto)
+	default:
+		err = fmt.Errorf("neither an OpenAPI document nor a proto file given")
+	}
+	if err != nil {
+		return st, "", ErrServiceProfile(err)
+	}
+
+	profile := serviceProfile{
+		APIVersion: serviceProfileAPIVersion,
+		Kind:       serviceProfileKind,
+		Metadata: profileMetadata{
+			Name:      profileName(opts.Service, namespace, opts.ClusterDomain),
+			Namespace: namespace,
+		},
+		Spec: serviceProfileSpec{Routes: routes},
+	}
+	manifest, err := yaml.Marshal(profile)
+	if err != nil {
+		return st, "", ErrServiceProfile(err)
+	}
+
+	if err = linkerd.applyManifest(manifest, del, namespace); err != nil {
+		return st, "", ErrServiceProfile(err)
+	}
+	if del {
+		return status.Removed, "", nil
+	}
+	return status.Deployed, string(manifest), nil
+}
+
+// profileName is the name of the ServiceProfile of a service, its fully qualified name
+func profileName(service, namespace, clusterDomain string) string {
+	return fmt.Sprintf("%s.%s.svc.%s", service, namespace, clusterDomain)
+}
+
+// openAPIRoutes generates a route for every operation of the OpenAPI document
+func openAPIRoutes(document string) ([]profileRoute, error) {
+	doc := openAPIDocument{}
+	if err := yaml.Unmarshal([]byte(document), &doc); err != nil {
+		return nil, err
+	}
+	if doc.Swagger == "" && doc.OpenAPI == "" {
+		return nil, fmt.Errorf("document is neither a Swagger 2.0 nor an OpenAPI 3 document")
+	}
+
+	// Swagger 2.0 has a base path, OpenAPI 3 carries it in the server URL
+	base := doc.BasePath
+	if len(doc.Servers) > 0 {
+		u, err := url.Parse(doc.Servers[0].URL)
+		if err != nil {
+			return nil, err
+		}
+		base = u.Path
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	routes := []profileRoute{}
+	for _, p := range paths {
+		for _, method := range httpMethods {
+			op, ok := doc.Paths[p][method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			path := base + p
+			route := profileRoute{
+				Name: fmt.Sprintf("%s %s", strings.ToUpper(method), path),
+				Condition: requestMatch{
+					Method:    strings.ToUpper(method),
+					PathRegex: pathRegex(path),
+				},
+			}
+			if retryable, ok := op[retryableExtension].(bool); ok {
+				route.IsRetryable = retryable
+			}
+			if timeout, ok := op[timeoutExtension].(string); ok {
+				route.Timeout = timeout
+			}
+			routes = append(routes, route)
+		}
+	}
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("document has no operations")
+	}
+	return routes, nil
+}
+
+// pathRegex turns an OpenAPI path template into a regular expression
+// matching any value of its parameters within a single segment
+func pathRegex(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if pathParam.MatchString(s) {
+			segments[i] = "[^/]*"
+		} else {
+			segments[i] = regexp.QuoteMeta(s)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// protoRoutes generates a route for every method of the gRPC services of the protobuf file
+func protoRoutes(proto string) ([]profileRoute, error) {
+	source := protoComment.ReplaceAllString(proto, "")
+
+	pkg := ""
+	if m := protoPackage.FindStringSubmatch(source); m != nil {
+		pkg = m[1] + "."
+	}
+
+	routes := []profileRoute{}
+	for _, loc := range protoService.FindAllStringSubmatchIndex(source, -1) {
+		service := source[loc[2]:loc[3]]
+		block, err := braceBlock(source, loc[1]-1)
+		if err != nil {
+			return nil, fmt.Errorf("service %s: %s", service, err)
+		}
+
+		for _, m := range protoRPCMethod.FindAllStringSubmatch(block, -1) {
+			path := fmt.Sprintf("/%s%s/%s", pkg, service, m[1])
+			routes = append(routes, profileRoute{
+				Name: fmt.Sprintf("POST %s", path),
+				Condition: requestMatch{
+					Method:    "POST",
+					PathRegex: regexp.QuoteMeta(path),
+				},
+			})
+		}
+	}
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("proto file declares no service methods")
+	}
+	return routes, nil
+}
+
+// braceBlock returns the contents of the block opened by the brace at the given offset
+func braceBlock(source string, open int) (string, error) {
+	depth := 0
+	for i := open; i < len(source); i++ {
+		switch source[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return source[open+1 : i], nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unbalanced braces")
+}
@@ -0,0 +1,140 @@
+package linkerd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		routes   []profileRoute
+		err      bool
+	}{
+		{
+			name: "swagger with base path and extensions",
+			document: `swagger: "2.0"
+basePath: /api/
+paths:
+  /books/{id}:
+    get:
+      x-linkerd-retryable: true
+      x-linkerd-timeout: 300ms
+    delete: {}
+`,
+			routes: []profileRoute{
+				{Name: "GET /api/books/{id}", Condition: requestMatch{Method: "GET", PathRegex: `/api/books/[^/]*`}, IsRetryable: true, Timeout: "300ms"},
+				{Name: "DELETE /api/books/{id}", Condition: requestMatch{Method: "DELETE", PathRegex: `/api/books/[^/]*`}},
+			},
+		},
+		{
+			name: "openapi with server url",
+			document: `openapi: 3.0.0
+servers:
+- url: https://books.example.com/v1
+paths:
+  /authors.json:
+    post: {}
+`,
+			routes: []profileRoute{
+				{Name: "POST /v1/authors.json", Condition: requestMatch{Method: "POST", PathRegex: `/v1/authors\.json`}},
+			},
+		},
+		{
+			name:     "neither swagger nor openapi",
+			document: `paths: {}`,
+			err:      true,
+		},
+		{
+			name: "no operations",
+			document: `openapi: 3.0.0
+paths:
+  /books: {}
+`,
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := openAPIRoutes(tt.document)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, got routes %v", routes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(routes, tt.routes) {
+				t.Errorf("got routes %v, want %v", routes, tt.routes)
+			}
+		})
+	}
+}
+
+func TestProtoRoutes(t *testing.T) {
+	tests := []struct {
+		name   string
+		proto  string
+		routes []profileRoute
+		err    bool
+	}{
+		{
+			name: "package and nested blocks",
+			proto: `syntax = "proto3";
+package books.v1;
+
+// service Commented { rpc Ignored(A) returns (B); }
+service Books {
+  rpc GetBook(GetBookRequest) returns (Book) {
+    option (google.api.http) = { get: "/books/{id}" };
+  }
+  /* rpc Hidden(A) returns (B); */
+  rpc ListBooks (ListBooksRequest) returns (stream Book);
+}
+`,
+			routes: []profileRoute{
+				{Name: "POST /books.v1.Books/GetBook", Condition: requestMatch{Method: "POST", PathRegex: `/books\.v1\.Books/GetBook`}},
+				{Name: "POST /books.v1.Books/ListBooks", Condition: requestMatch{Method: "POST", PathRegex: `/books\.v1\.Books/ListBooks`}},
+			},
+		},
+		{
+			name:  "without package",
+			proto: `service Authors { rpc Get(A) returns (B); }`,
+			routes: []profileRoute{
+				{Name: "POST /Authors/Get", Condition: requestMatch{Method: "POST", PathRegex: `/Authors/Get`}},
+			},
+		},
+		{
+			name:  "unbalanced braces",
+			proto: `service Authors { rpc Get(A) returns (B) {`,
+			err:   true,
+		},
+		{
+			name:  "no services",
+			proto: `message Book { string id = 1; }`,
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := protoRoutes(tt.proto)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, got routes %v", routes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(routes, tt.routes) {
+				t.Errorf("got routes %v, want %v", routes, tt.routes)
+			}
+		})
+	}
+}
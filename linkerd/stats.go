@@ -0,0 +1,177 @@
+package linkerd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	statsByDeployment = "deployment"
+	statsByRoute      = "route"
+	statsByEdge       = "edge"
+
+	defaultStatsWindow = time.Minute
+
+	statsRequestQuery = `sum(rate(%[1]srequest_total{%[2]s}[%[3]s])) by (%[4]s)`
+	statsSuccessQuery = `sum(rate(%[1]sresponse_total{%[2]s, classification="success"}[%[3]s])) by (%[4]s) / sum(rate(%[1]sresponse_total{%[2]s}[%[3]s])) by (%[4]s)`
+	statsLatencyQuery = `histogram_quantile(%[5]s, sum(rate(%[1]sresponse_latency_ms_bucket{%[2]s}[%[3]s])) by (le, %[4]s))`
+)
+
+// statsGrouping is how the metrics are aggregated for a kind of stats
+type statsGrouping struct {
+	// prefix of the metric names, the route metrics are prefixed with "route_"
+	prefix    string
+	direction string
+	labels    []string
+}
+
+var statsGroupings = map[string]statsGrouping{
+	statsByDeployment: {direction: "inbound", labels: []string{"deployment"}},
+	statsByRoute:      {prefix: "route_", direction: "inbound", labels: []string{"deployment", "rt_route"}},
+	statsByEdge:       {direction: "outbound", labels: []string{"deployment", "dst_namespace", "dst_deployment"}},
+}
+
+// StatsOptions is the body of the stats operation
+type StatsOptions struct {
+	// By is one of deployment, route and edge, defaults to deployment
+	By string `json:"by,omitempty"`
+	// Deployments to report on, all deployments of the namespace if empty
+	Deployments []string `json:"deployments,omitempty"`
+	// Window is the time range the stats are computed over, defaults to one minute, at least 20s
+	Window string `json:"window,omitempty"`
+
+	PrometheusOptions `json:",inline"`
+}
+
+// StatsReport are the golden metrics of the namespace
+type StatsReport struct {
+	Namespace string     `json:"namespace"`
+	By        string     `json:"by"`
+	Window    string     `json:"window"`
+	Rows      []StatsRow `json:"rows"`
+}
+
+// StatsRow are the golden metrics of a deployment, a route of a deployment, or an edge from
+// a deployment to another. Success rate and latencies are missing without responses
+type StatsRow struct {
+	Deployment    string `json:"deployment"`
+	Route         string `json:"route,omitempty"`
+	DstNamespace  string `json:"dstNamespace,omitempty"`
+	DstDeployment string `json:"dstDeployment,omitempty"`
+
+	RequestRate  float64  `json:"requestRate"`
+	SuccessRate  *float64 `json:"successRate,omitempty"`
+	LatencyMsP50 *float64 `json:"latencyMsP50,omitempty"`
+	LatencyMsP95 *float64 `json:"latencyMsP95,omitempty"`
+	LatencyMsP99 *float64 `json:"latencyMsP99,omitempty"`
+}
+
+// stats queries the success rate, the request rate and the latency percentiles
+// of the meshed deployments of the namespace from the Linkerd Prometheus
+func (linkerd *Linkerd) stats(namespace, body string) (*StatsReport, error) {
+	opts := StatsOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return nil, err
+	}
+	if opts.By == "" {
+		opts.By = statsByDeployment
+	}
+	grouping, ok := statsGroupings[opts.By]
+	if !ok {
+		return nil, ErrStats(fmt.Errorf("stats by %q are not supported, use deployment, route or edge", opts.By))
+	}
+	window, err := parseQueryWindow(opts.Window, defaultStatsWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	prom, err := linkerd.prometheus(opts.PrometheusOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer prom.Close()
+
+	selector := fmt.Sprintf(`namespace="%s", direction="%s"`, namespace, grouping.direction)
+	if len(opts.Deployments) > 0 {
+		selector += fmt.Sprintf(`, deployment=~"%s"`, strings.Join(opts.Deployments, "|"))
+	}
+	rangeVector := fmt.Sprintf("%ds", int(window.Seconds()))
+	by := strings.Join(grouping.labels, ", ")
+
+	rows := map[string]*StatsRow{}
+	row := func(metric map[string]string) *StatsRow {
+		values := make([]string, 0, len(grouping.labels))
+		for _, l := range grouping.labels {
+			values = append(values, metric[l])
+		}
+		key := strings.Join(values, "|")
+		if r, ok := rows[key]; ok {
+			return r
+		}
+		r := &StatsRow{
+			Deployment:    metric["deployment"],
+			Route:         metric["rt_route"],
+			DstNamespace:  metric["dst_namespace"],
+			DstDeployment: metric["dst_deployment"],
+		}
+		rows[key] = r
+		return r
+	}
+
+	samples, err := prom.query(fmt.Sprintf(statsRequestQuery, grouping.prefix, selector, rangeVector, by))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range samples {
+		row(s.Metric).RequestRate = s.Value
+	}
+
+	samples, err = prom.query(fmt.Sprintf(statsSuccessQuery, grouping.prefix, selector, rangeVector, by))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range samples {
+		v := s.Value
+		row(s.Metric).SuccessRate = &v
+	}
+
+	quantiles := []struct {
+		quantile string
+		set      func(*StatsRow, *float64)
+	}{
+		{"0.5", func(r *StatsRow, v *float64) { r.LatencyMsP50 = v }},
+		{"0.95", func(r *StatsRow, v *float64) { r.LatencyMsP95 = v }},
+		{"0.99", func(r *StatsRow, v *float64) { r.LatencyMsP99 = v }},
+	}
+	for _, q := range quantiles {
+		samples, err = prom.query(fmt.Sprintf(statsLatencyQuery, grouping.prefix, selector, rangeVector, by, q.quantile))
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range samples {
+			v := s.Value
+			q.set(row(s.Metric), &v)
+		}
+	}
+
+	report := &StatsReport{Namespace: namespace, By: opts.By, Window: window.String(), Rows: []StatsRow{}}
+	for _, r := range rows {
+		report.Rows = append(report.Rows, *r)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Deployment != b.Deployment {
+			return a.Deployment < b.Deployment
+		}
+		if a.Route != b.Route {
+			return a.Route < b.Route
+		}
+		if a.DstNamespace != b.DstNamespace {
+			return a.DstNamespace < b.DstNamespace
+		}
+		return a.DstDeployment < b.DstDeployment
+	})
+	return report, nil
+}
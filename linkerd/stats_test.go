@@ -0,0 +1,111 @@
+package linkerd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	successRate, p50, p95, p99 := 0.9, 10.0, 20.0, 30.0
+
+	tests := []struct {
+		name    string
+		by      string
+		metrics []map[string]string
+		// fragments every query has to contain, the grouping labels close the by clause
+		query []string
+		rows  []StatsRow
+	}{
+		{
+			name: "deployment",
+			metrics: []map[string]string{
+				{"deployment": "web"},
+				{"deployment": "api"},
+			},
+			query: []string{`direction="inbound"`, `deployment)`, `[60s]`},
+			rows: []StatsRow{
+				{Deployment: "api", RequestRate: 5, SuccessRate: &successRate, LatencyMsP50: &p50, LatencyMsP95: &p95, LatencyMsP99: &p99},
+				{Deployment: "web", RequestRate: 5, SuccessRate: &successRate, LatencyMsP50: &p50, LatencyMsP95: &p95, LatencyMsP99: &p99},
+			},
+		},
+		{
+			name: "route",
+			by:   statsByRoute,
+			metrics: []map[string]string{
+				{"deployment": "web", "rt_route": "GET /books"},
+				{"deployment": "web", "rt_route": "POST /books"},
+			},
+			query: []string{`route_`, `direction="inbound"`, `deployment, rt_route)`},
+			rows: []StatsRow{
+				{Deployment: "web", Route: "GET /books", RequestRate: 5, SuccessRate: &successRate, LatencyMsP50: &p50, LatencyMsP95: &p95, LatencyMsP99: &p99},
+				{Deployment: "web", Route: "POST /books", RequestRate: 5, SuccessRate: &successRate, LatencyMsP50: &p50, LatencyMsP95: &p95, LatencyMsP99: &p99},
+			},
+		},
+		{
+			name: "edge",
+			by:   statsByEdge,
+			metrics: []map[string]string{
+				{"deployment": "web", "dst_namespace": "books", "dst_deployment": "authors"},
+			},
+			query: []string{`direction="outbound"`, `deployment, dst_namespace, dst_deployment)`},
+			rows: []StatsRow{
+				{Deployment: "web", DstNamespace: "books", DstDeployment: "authors", RequestRate: 5, SuccessRate: &successRate, LatencyMsP50: &p50, LatencyMsP95: &p95, LatencyMsP99: &p99},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prom := newFakePrometheus(t, tt.metrics)
+
+			body, _ := json.Marshal(StatsOptions{By: tt.by, PrometheusOptions: PrometheusOptions{PrometheusURL: prom.URL}})
+			report, err := (&Linkerd{}).stats("books", string(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Namespace != "books" || report.Window != "1m0s" {
+				t.Errorf("got namespace %q and window %q", report.Namespace, report.Window)
+			}
+			if !reflect.DeepEqual(report.Rows, tt.rows) {
+				got, _ := json.Marshal(report.Rows)
+				want, _ := json.Marshal(tt.rows)
+				t.Errorf("got rows %s, want %s", got, want)
+			}
+
+			if len(prom.queries) != 5 {
+				t.Errorf("got %d queries, want 5", len(prom.queries))
+			}
+			for _, q := range prom.queries {
+				if !strings.Contains(q, `namespace="books"`) {
+					t.Errorf("query %s is not restricted to the namespace", q)
+				}
+				for _, fragment := range tt.query {
+					if !strings.Contains(q, fragment) {
+						t.Errorf("query %s does not contain %s", q, fragment)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestStatsOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "unknown grouping", body: `{"by": "pod"}`},
+		{name: "invalid window", body: `{"window": "a minute"}`},
+		{name: "window below the scrape interval", body: `{"window": "10s"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := (&Linkerd{}).stats("books", tt.body); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package linkerd
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/layer5io/meshery-adapter-library/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	trustAnchorRotationCheckpoint = "trust-anchor-rotation"
+
+	phaseBundleRoots    = "bundle-roots"
+	phaseRollBundle     = "roll-data-plane"
+	phaseSwitchIssuer   = "switch-issuer"
+	phaseDropOldRoots   = "drop-old-roots"
+	phaseRollFinalRoots = "roll-data-plane-final"
+
+	proxyTrustAnchorsEnv = "LINKERD2_PROXY_IDENTITY_TRUST_ANCHORS"
+)
+
+// RotateTrustAnchorOptions is the body of the rotate-trust-anchor operation
+type RotateTrustAnchorOptions struct {
+	// NewTrustAnchorCert and NewTrustAnchorKey are the PEM encoded certificate
+	// and private key of the trust anchor to rotate to. The key is used to
+	// sign the issuer which replaces the current one
+	NewTrustAnchorCert string `json:"newTrustAnchorCert"`
+	NewTrustAnchorKey  string `json:"newTrustAnchorKey"`
+
+	// IssuerValidity is the validity of the new issuer certificate, defaults to one year
+	IssuerValidity string `json:"issuerValidity,omitempty"`
+	// MaxUnavailable is the number or percentage of workloads restarted
+	// at once when the data plane is rolled, defaults to one
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// trustAnchorRotation is the persisted progress of a trust anchor rotation
+type trustAnchorRotation struct {
+	// Fingerprint is the SHA-256 fingerprint of the new trust anchor, a rotation
+	// can only be resumed with the same trust anchor it was started with
+	Fingerprint string `json:"fingerprint"`
+	// OldRoots are the PEM encoded trust anchors the rotation started from
+	OldRoots  string   `json:"oldRoots"`
+	Completed []string `json:"completed"`
+	UpdatedAt string   `json:"updatedAt"`
+}
+
+func (r *trustAnchorRotation) isCompleted(phase string) bool {
+	for _, p := range r.Completed {
+		if p == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// rotationPhase is a single step of the trust anchor rotation, run performs the
+// step and returns only after its verification gate has passed
+type rotationPhase struct {
+	name string
+	run  func() error
+}
+
+// rotateTrustAnchor replaces the trust anchor of the control plane without downtime:
+// the new anchor is bundled with the old ones, the data plane is rolled to pick up the
+// bundle, the issuer is switched to one signed by the new anchor and finally the old
+// anchors are dropped and the data plane is rolled again. The progress is persisted after
+// every phase so that an interrupted rotation continues where it stopped when the
+// operation is requested again. A delete operation abandons the persisted progress
+func (linkerd *Linkerd) rotateTrustAnchor(opID, namespace, body string, del bool) (string, error) {
+	st := status.Patching
+	if namespace == "" {
+		namespace = defaultControlPlaneNamespace
+	}
+
+	if del {
+		if err := linkerd.clearCheckpoint(namespace, trustAnchorRotationCheckpoint); err != nil {
+			return status.Removing, err
+		}
+		return status.Removed, nil
+	}
+
+	opts := RotateTrustAnchorOptions{}
+	if err := parseOperationBody(body, &opts); err != nil {
+		return st, err
+	}
+	validity := defaultIssuerValidity
+	if opts.IssuerValidity != "" {
+		d, err := time.ParseDuration(opts.IssuerValidity)
+		if err != nil {
+			return st, ErrParseOperationBody(err)
+		}
+		validity = d
+	}
+
+	newRoots, err := parseCertificates([]byte(opts.NewTrustAnchorCert))
+	if err != nil {
+		return st, ErrIdentityCertificate(err)
+	}
+	newAnchor, newKey, err := matchTrustAnchor(newRoots[:1], []byte(opts.NewTrustAnchorCert), []byte(opts.NewTrustAnchorKey))
+	if err != nil {
+		return st, ErrIdentityCertificate(err)
+	}
+	fingerprint := sha256.Sum256(newAnchor.Raw)
+
+	// The issuer is switched halfway through, cert-manager would overwrite it
+	if err = linkerd.checkIssuerUnmanaged(namespace); err != nil {
+		return st, err
+	}
+
+	state := &trustAnchorRotation{}
+	found, err := linkerd.loadCheckpoint(namespace, trustAnchorRotationCheckpoint, state)
+	if err != nil {
+		return st, err
+	}
+	if found && state.Fingerprint != hex.EncodeToString(fingerprint[:]) {
+		return st, ErrTrustAnchorRotation(fmt.Errorf("a rotation to a different trust anchor is in progress, delete it before starting a new one"), "resume")
+	}
+	if found {
+		linkerd.streamProgress(opID, "Resuming trust anchor rotation", fmt.Sprintf("Completed phases: %s", strings.Join(state.Completed, ", ")))
+	} else {
+		current, err := linkerd.getTrustAnchors(namespace)
+		if err != nil {
+			return st, ErrIdentityCertificate(err)
+		}
+		state.Fingerprint = hex.EncodeToString(fingerprint[:])
+		state.OldRoots = string(encodeCertificates(current))
+		if err = linkerd.saveCheckpoint(namespace, trustAnchorRotationCheckpoint, state); err != nil {
+			return st, err
+		}
+	}
+
+	oldRoots, err := parseCertificates([]byte(state.OldRoots))
+	if err != nil {
+		return st, ErrIdentityCertificate(err)
+	}
+	version, err := linkerd.getControlPlaneVersion(namespace)
+	if err != nil {
+		return st, ErrTrustAnchorRotation(err, "resume")
+	}
+
+	phases := []rotationPhase{
+		{
+			name: phaseBundleRoots,
+			run: func() error {
+				return linkerd.upgradeTrustAnchors(version, namespace, append(withoutCertificate(oldRoots, newAnchor), newAnchor))
+			},
+		},
+		{
+			name: phaseRollBundle,
+			run: func() error {
+				return linkerd.rollDataPlane(opID, namespace, newAnchor, false, opts.MaxUnavailable)
+			},
+		},
+		{
+			name: phaseSwitchIssuer,
+			run: func() error {
+				issuer, err := linkerd.replaceIssuer(opID, namespace, newAnchor, newKey, validity)
+				if err != nil {
+					return err
+				}
+				return linkerd.verifyIssuedCertificate(opID, namespace, issuer, []*x509.Certificate{newAnchor})
+			},
+		},
+		{
+			name: phaseDropOldRoots,
+			run: func() error {
+				return linkerd.upgradeTrustAnchors(version, namespace, []*x509.Certificate{newAnchor})
+			},
+		},
+		{
+			name: phaseRollFinalRoots,
+			run: func() error {
+				return linkerd.rollDataPlane(opID, namespace, newAnchor, true, opts.MaxUnavailable)
+			},
+		},
+	}
+
+	if err := linkerd.runRotationPhases(opID, namespace, state, phases); err != nil {
+		return st, err
+	}
+
+	return status.Patched, nil
+}
+
+// runRotationPhases runs the phases the rotation has not completed yet in order, the
+// progress is persisted after every phase and cleared once the last one completed
+func (linkerd *Linkerd) runRotationPhases(opID, namespace string, state *trustAnchorRotation, phases []rotationPhase) error {
+	for _, phase := range phases {
+		if state.isCompleted(phase.name) {
+			continue
+		}
+
+		linkerd.streamProgress(opID, fmt.Sprintf("Trust anchor rotation phase %s started", phase.name), "")
+		if err := phase.run(); err != nil {
+			return ErrTrustAnchorRotation(err, phase.name)
+		}
+
+		state.Completed = append(state.Completed, phase.name)
+		state.UpdatedAt = time.Now().Format(time.RFC3339)
+		if err := linkerd.saveCheckpoint(namespace, trustAnchorRotationCheckpoint, state); err != nil {
+			return err
+		}
+		linkerd.streamProgress(opID, fmt.Sprintf("Trust anchor rotation phase %s completed", phase.name), "Verification gate passed")
+	}
+
+	return linkerd.clearCheckpoint(namespace, trustAnchorRotationCheckpoint)
+}
+
+// upgradeTrustAnchors configures the control plane with the given trust anchors using
+// "linkerd upgrade", waits for the control plane to roll out and verifies the result
+func (linkerd *Linkerd) upgradeTrustAnchors(version, namespace string, anchors []*x509.Certificate) error {
+	file, err := writeTempFile("linkerd-trust-anchors-*.crt", encodeCertificates(anchors))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+
+	manifest, err := linkerd.runLinkerdCLI(version, "upgrade", "--linkerd-namespace", namespace, "--identity-trust-anchors-file", file)
+	if err != nil {
+		return err
+	}
+	if err = linkerd.applyManifest([]byte(manifest), false, namespace); err != nil {
+		return err
+	}
+
+	controlPlane, err := linkerd.listWorkloads(namespace, isMeshed)
+	if err != nil {
+		return err
+	}
+	for _, w := range controlPlane {
+		if err = linkerd.waitForWorkload(w, defaultRolloutTimeout); err != nil {
+			return err
+		}
+	}
+
+	configured, err := linkerd.getTrustAnchors(namespace)
+	if err != nil {
+		return err
+	}
+	if len(configured) != len(anchors) {
+		return fmt.Errorf("control plane has %d trust anchors configured, expected %d", len(configured), len(anchors))
+	}
+	for _, anchor := range anchors {
+		if len(withoutCertificate(configured, anchor)) == len(configured) {
+			return fmt.Errorf("trust anchor %s is not configured in the control plane", anchor.Subject.CommonName)
+		}
+	}
+
+	return nil
+}
+
+// rollDataPlane restarts the meshed workloads outside of the control plane namespace in
+// waves and verifies that the proxies of every wave trust the given anchor, and only that
+// one if exclusive is set. Meshed pods no restarted workload controls, such as bare pods
+// and the pods of jobs, are not replaced by the roll and only reported as warnings
+func (linkerd *Linkerd) rollDataPlane(opID, namespace string, anchor *x509.Certificate, exclusive bool, maxUnavailable *intstr.IntOrString) error {
+	workloads, err := linkerd.listWorkloads("", func(pod *corev1.Pod) bool {
+		return pod.Namespace != namespace && isMeshed(pod)
+	})
+	if err != nil {
+		return err
+	}
+	size, err := waveSize(maxUnavailable, len(workloads))
+	if err != nil {
+		return err
+	}
+	err = linkerd.restartInWaves(opID, workloads, size, defaultRolloutTimeout, func(wave []workload) error {
+		return linkerd.verifyProxyTrustAnchors(wave, anchor, exclusive)
+	})
+	if err != nil {
+		return err
+	}
+
+	unowned, err := linkerd.unownedStaleProxies(namespace, anchor, exclusive)
+	if err != nil {
+		return err
+	}
+	if len(unowned) > 0 {
+		linkerd.streamProgress(opID, fmt.Sprintf("%d pods keep outdated trust anchors until they are recreated", len(unowned)), strings.Join(unowned, ", "))
+	}
+	return nil
+}
+
+// verifyProxyTrustAnchors checks the trust anchors the pods of the workloads were injected with
+func (linkerd *Linkerd) verifyProxyTrustAnchors(workloads []workload, anchor *x509.Certificate, exclusive bool) error {
+	var stale []string
+	for _, w := range workloads {
+		pods, err := linkerd.workloadPods(w)
+		if err != nil {
+			return err
+		}
+		names, err := staleProxies(pods, anchor, exclusive)
+		if err != nil {
+			return err
+		}
+		stale = append(stale, names...)
+	}
+
+	if len(stale) > 0 {
+		return fmt.Errorf("proxies with outdated trust anchors: %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// unownedStaleProxies returns the meshed pods outside of the control plane namespace with
+// outdated trust anchors which are not controlled by a restartable workload
+func (linkerd *Linkerd) unownedStaleProxies(namespace string, anchor *x509.Certificate, exclusive bool) ([]string, error) {
+	pods, err := linkerd.listMeshedPods("")
+	if err != nil {
+		return nil, err
+	}
+
+	unowned := []corev1.Pod{}
+	for i := range pods {
+		if pods[i].Namespace == namespace {
+			continue
+		}
+		_, ok, err := linkerd.podOwner(&pods[i])
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			unowned = append(unowned, pods[i])
+		}
+	}
+	return staleProxies(unowned, anchor, exclusive)
+}
+
+// staleProxies returns the meshed pods whose proxies do not trust the anchor, or trust
+// other anchors as well if exclusive is set
+func staleProxies(pods []corev1.Pod, anchor *x509.Certificate, exclusive bool) ([]string, error) {
+	var stale []string
+	for i := range pods {
+		proxy := proxyContainer(&pods[i])
+		if proxy == nil {
+			continue
+		}
+		for _, env := range proxy.Env {
+			// Proxies reading the anchors from a configmap are covered by the control plane checks
+			if env.Name != proxyTrustAnchorsEnv || env.Value == "" {
+				continue
+			}
+			roots, err := parseCertificates([]byte(env.Value))
+			if err != nil {
+				return nil, err
+			}
+			others := withoutCertificate(roots, anchor)
+			if len(others) == len(roots) || (exclusive && len(others) > 0) {
+				stale = append(stale, fmt.Sprintf("%s/%s", pods[i].Namespace, pods[i].Name))
+			}
+		}
+	}
+	return stale, nil
+}
+
+// withoutCertificate returns the certificates which are not equal to cert
+func withoutCertificate(certs []*x509.Certificate, cert *x509.Certificate) []*x509.Certificate {
+	result := []*x509.Certificate{}
+	for _, c := range certs {
+		if !c.Equal(cert) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// encodeCertificates PEM encodes the certificates into a bundle
+func encodeCertificates(certs []*x509.Certificate) []byte {
+	var bundle []byte
+	for _, c := range certs {
+		bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return bundle
+}
@@ -0,0 +1,152 @@
+package linkerd
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunRotationPhases(t *testing.T) {
+	all := []string{phaseBundleRoots, phaseRollBundle, phaseSwitchIssuer, phaseDropOldRoots, phaseRollFinalRoots}
+
+	tests := []struct {
+		name      string
+		completed []string
+		fail      string
+		// ran are the phases expected to run, in order
+		ran []string
+		// checkpoint are the completed phases persisted, nil if the checkpoint is cleared
+		checkpoint []string
+	}{
+		{
+			name: "all phases",
+			ran:  all,
+		},
+		{
+			name:      "resume",
+			completed: []string{phaseBundleRoots, phaseRollBundle},
+			ran:       []string{phaseSwitchIssuer, phaseDropOldRoots, phaseRollFinalRoots},
+		},
+		{
+			name:       "failed phase",
+			completed:  []string{phaseBundleRoots},
+			fail:       phaseSwitchIssuer,
+			ran:        []string{phaseRollBundle, phaseSwitchIssuer},
+			checkpoint: []string{phaseBundleRoots, phaseRollBundle},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			linkerd, _ := newFakeLinkerd(t)
+			state := &trustAnchorRotation{Fingerprint: "fingerprint", Completed: tt.completed}
+			if err := linkerd.saveCheckpoint("linkerd", trustAnchorRotationCheckpoint, state); err != nil {
+				t.Fatal(err)
+			}
+
+			ran := []string{}
+			phases := []rotationPhase{}
+			for _, name := range all {
+				name := name
+				phases = append(phases, rotationPhase{name: name, run: func() error {
+					ran = append(ran, name)
+					if name == tt.fail {
+						return errors.New("verification gate failed")
+					}
+					return nil
+				}})
+			}
+
+			err := linkerd.runRotationPhases("op", "linkerd", state, phases)
+			if (err != nil) != (tt.fail != "") {
+				t.Fatalf("got error %v", err)
+			}
+			if !reflect.DeepEqual(ran, tt.ran) {
+				t.Errorf("ran phases %v, want %v", ran, tt.ran)
+			}
+
+			saved := &trustAnchorRotation{}
+			found, err := linkerd.loadCheckpoint("linkerd", trustAnchorRotationCheckpoint, saved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.checkpoint == nil {
+				if found {
+					t.Errorf("checkpoint with phases %v was not cleared", saved.Completed)
+				}
+				return
+			}
+			if !found || !reflect.DeepEqual(saved.Completed, tt.checkpoint) || saved.Fingerprint != "fingerprint" {
+				t.Errorf("got checkpoint %+v, want phases %v", saved, tt.checkpoint)
+			}
+		})
+	}
+}
+
+func TestClearCheckpoint(t *testing.T) {
+	linkerd, _ := newFakeLinkerd(t)
+	if err := linkerd.clearCheckpoint("linkerd", trustAnchorRotationCheckpoint); err != nil {
+		t.Errorf("clearing a missing checkpoint: %s", err)
+	}
+
+	if err := linkerd.saveCheckpoint("linkerd", trustAnchorRotationCheckpoint, &trustAnchorRotation{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := linkerd.clearCheckpoint("linkerd", trustAnchorRotationCheckpoint); err != nil {
+		t.Fatal(err)
+	}
+	_, err := linkerd.KubeClient.CoreV1().ConfigMaps("linkerd").Get(context.TODO(), checkpointPrefix+trustAnchorRotationCheckpoint, metav1.GetOptions{})
+	if !kubeerror.IsNotFound(err) {
+		t.Errorf("checkpoint configmap still exists: %v", err)
+	}
+}
+
+func TestStaleProxies(t *testing.T) {
+	oldAnchor, oldPEM, _ := newTestTrustAnchor(t, "old.linkerd.cluster.local")
+	newAnchor, newPEM, _ := newTestTrustAnchor(t, "new.linkerd.cluster.local")
+
+	pod := func(name, roots string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "books"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: proxyContainerName,
+				Env:  []corev1.EnvVar{{Name: proxyTrustAnchorsEnv, Value: roots}},
+			}}},
+		}
+	}
+	pods := []corev1.Pod{
+		pod("old", string(oldPEM)),
+		pod("bundle", string(oldPEM)+string(newPEM)),
+		pod("new", string(newPEM)),
+		{ObjectMeta: metav1.ObjectMeta{Name: "unmeshed", Namespace: "books"}},
+	}
+
+	tests := []struct {
+		name      string
+		anchor    *x509.Certificate
+		exclusive bool
+		stale     []string
+	}{
+		{name: "bundle", anchor: newAnchor, stale: []string{"books/old"}},
+		{name: "exclusive", anchor: newAnchor, exclusive: true, stale: []string{"books/old", "books/bundle"}},
+		{name: "old anchor", anchor: oldAnchor, stale: []string{"books/new"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stale, err := staleProxies(pods, tt.anchor, tt.exclusive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(stale, tt.stale) {
+				t.Errorf("got stale proxies %v, want %v", stale, tt.stale)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package linkerd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const defaultMaxUnavailable = 1
+
+// waves splits the workloads into consecutive waves of at most size workloads
+func waves(workloads []workload, size int) [][]workload {
+	if size < 1 {
+		size = 1
+	}
+
+	result := [][]workload{}
+	for start := 0; start < len(workloads); start += size {
+		end := start + size
+		if end > len(workloads) {
+			end = len(workloads)
+		}
+		result = append(result, workloads[start:end])
+	}
+	return result
+}
+
+// waveSize resolves the number of workloads restarted at once from an absolute
+// number or a percentage of the workloads, it is at least one
+func waveSize(maxUnavailable *intstr.IntOrString, total int) (int, error) {
+	if maxUnavailable == nil {
+		return defaultMaxUnavailable, nil
+	}
+	size, err := intstr.GetValueFromIntOrPercent(maxUnavailable, total, false)
+	if err != nil {
+		return 0, ErrParseOperationBody(err)
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size, nil
+}
+
+// restartInWaves restarts the workloads wave by wave, a wave is only started once
+// every workload of the previous one is ready again and passed the check, if any
+func (linkerd *Linkerd) restartInWaves(opID string, workloads []workload, size int, timeout time.Duration, check func([]workload) error) error {
+	all := waves(workloads, size)
+	for i, wave := range all {
+		names := make([]string, 0, len(wave))
+		for _, w := range wave {
+			names = append(names, w.String())
+		}
+
+		linkerd.streamProgress(opID, fmt.Sprintf("Restarting wave %d of %d", i+1, len(all)), strings.Join(names, ", "))
+		if err := linkerd.restartWorkloads(wave, timeout); err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(wave); err != nil {
+				return err
+			}
+		}
+		linkerd.streamProgress(opID, fmt.Sprintf("Wave %d of %d is ready", i+1, len(all)), strings.Join(names, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package linkerd
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestWaveSize(t *testing.T) {
+	percent := func(s string) *intstr.IntOrString {
+		v := intstr.FromString(s)
+		return &v
+	}
+	number := func(i int) *intstr.IntOrString {
+		v := intstr.FromInt(i)
+		return &v
+	}
+
+	tests := []struct {
+		name           string
+		maxUnavailable *intstr.IntOrString
+		total          int
+		size           int
+		err            bool
+	}{
+		{name: "default", total: 10, size: defaultMaxUnavailable},
+		{name: "number", maxUnavailable: number(3), total: 10, size: 3},
+		{name: "zero is at least one", maxUnavailable: number(0), total: 10, size: 1},
+		{name: "percentage rounds down", maxUnavailable: percent("25%"), total: 10, size: 2},
+		{name: "small percentage is at least one", maxUnavailable: percent("10%"), total: 5, size: 1},
+		{name: "all at once", maxUnavailable: percent("100%"), total: 7, size: 7},
+		{name: "invalid percentage", maxUnavailable: percent("half"), total: 10, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := waveSize(tt.maxUnavailable, tt.total)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected an error, got size %d", size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != tt.size {
+				t.Errorf("got size %d, want %d", size, tt.size)
+			}
+		})
+	}
+}
+
+func TestWaves(t *testing.T) {
+	workloads := []workload{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+
+	tests := []struct {
+		name  string
+		size  int
+		sizes []int
+	}{
+		{name: "one by one", size: 1, sizes: []int{1, 1, 1, 1, 1}},
+		{name: "last wave is smaller", size: 2, sizes: []int{2, 2, 1}},
+		{name: "single wave", size: 10, sizes: []int{5}},
+		{name: "invalid size is one", size: 0, sizes: []int{1, 1, 1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			all := waves(workloads, tt.size)
+			if len(all) != len(tt.sizes) {
+				t.Fatalf("got %d waves, want %d", len(all), len(tt.sizes))
+			}
+			for i, wave := range all {
+				if len(wave) != tt.sizes[i] {
+					t.Errorf("wave %d has %d workloads, want %d", i, len(wave), tt.sizes[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package linkerd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	deploymentKind  = "Deployment"
+	statefulSetKind = "StatefulSet"
+	daemonSetKind   = "DaemonSet"
+	jobKind         = "Job"
+	cronJobKind     = "CronJob"
+
+	replicaSetKind = "ReplicaSet"
+
+	proxyContainerName = "linkerd-proxy"
+
+	defaultRolloutTimeout = 5 * time.Minute
+	rolloutPollInterval   = 2 * time.Second
+)
+
+// workload identifies a kubernetes workload managed by the adapter
+type workload struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+func (w workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// restartWorkload triggers a rollout of the workload the same way
+// "kubectl rollout restart" does, by annotating its pod template
+func (linkerd *Linkerd) restartWorkload(w workload) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":"%s"}}}}}`, time.Now().Format(time.RFC3339)))
+
+	var err error
+	apps := linkerd.KubeClient.AppsV1()
+	switch w.Kind {
+	case deploymentKind:
+		_, err = apps.Deployments(w.Namespace).Patch(context.TODO(), w.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case statefulSetKind:
+		_, err = apps.StatefulSets(w.Namespace).Patch(context.TODO(), w.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	case daemonSetKind:
+		_, err = apps.DaemonSets(w.Namespace).Patch(context.TODO(), w.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("unsupported workload kind %s", w.Kind)
+	}
+	if err != nil {
+		return ErrRollout(err, w.String())
+	}
+
+	return nil
+}
+
+// waitForWorkload waits until the latest generation of the workload is rolled out
+// and all of its replicas are updated and available
+func (linkerd *Linkerd) waitForWorkload(w workload, timeout time.Duration) error {
+	err := wait.PollImmediate(rolloutPollInterval, timeout, func() (bool, error) {
+		return linkerd.isWorkloadReady(w)
+	})
+	if err != nil {
+		return ErrRollout(err, w.String())
+	}
+
+	return nil
+}
+
+func (linkerd *Linkerd) isWorkloadReady(w workload) (bool, error) {
+	apps := linkerd.KubeClient.AppsV1()
+	switch w.Kind {
+	case deploymentKind:
+		d, err := apps.Deployments(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		return d.Status.ObservedGeneration >= d.Generation &&
+			d.Status.UpdatedReplicas == replicas &&
+			d.Status.Replicas == replicas &&
+			d.Status.AvailableReplicas == replicas, nil
+	case statefulSetKind:
+		s, err := apps.StatefulSets(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		replicas := int32(1)
+		if s.Spec.Replicas != nil {
+			replicas = *s.Spec.Replicas
+		}
+		return s.Status.ObservedGeneration >= s.Generation &&
+			s.Status.UpdatedReplicas == replicas &&
+			s.Status.ReadyReplicas == replicas, nil
+	case daemonSetKind:
+		ds, err := apps.DaemonSets(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return ds.Status.ObservedGeneration >= ds.Generation &&
+			ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+			ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled, nil
+	case jobKind:
+		j, err := linkerd.KubeClient.BatchV1().Jobs(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		// Jobs are never rolled out, they are ready once their pods have been created
+		return j.Status.Active > 0 || j.Status.Succeeded > 0 || j.Status.Failed > 0, nil
+	}
+
+	return false, fmt.Errorf("unsupported workload kind %s", w.Kind)
+}
+
+// isMeshed returns true if the pod has a linkerd proxy container
+func isMeshed(pod *corev1.Pod) bool {
+	return proxyContainer(pod) != nil
+}
+
+// proxyContainer returns the linkerd proxy container of the pod, if any
+func proxyContainer(pod *corev1.Pod) *corev1.Container {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == proxyContainerName {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// listMeshedPods returns the running meshed pods in the namespace, or in all namespaces if it is empty
+func (linkerd *Linkerd) listMeshedPods(namespace string) ([]corev1.Pod, error) {
+	pods, err := linkerd.KubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	meshed := []corev1.Pod{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil && isMeshed(pod) {
+			meshed = append(meshed, *pod)
+		}
+	}
+	return meshed, nil
+}
+
+// podOwner resolves the workload controlling the pod, it returns false
+// for pods which are not controlled by a restartable workload
+func (linkerd *Linkerd) podOwner(pod *corev1.Pod) (workload, bool, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return workload{}, false, nil
+	}
+
+	switch ref.Kind {
+	case replicaSetKind:
+		rs, err := linkerd.KubeClient.AppsV1().ReplicaSets(pod.Namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return workload{}, false, err
+		}
+		owner := metav1.GetControllerOf(rs)
+		if owner == nil || owner.Kind != deploymentKind {
+			return workload{}, false, nil
+		}
+		return workload{Kind: deploymentKind, Namespace: pod.Namespace, Name: owner.Name}, true, nil
+	case statefulSetKind, daemonSetKind:
+		return workload{Kind: ref.Kind, Namespace: pod.Namespace, Name: ref.Name}, true, nil
+	}
+
+	return workload{}, false, nil
+}
+
+// listWorkloads returns the workloads in the namespace, or in all namespaces if it is
+// empty, which control at least one pod accepted by the filter
+func (linkerd *Linkerd) listWorkloads(namespace string, filter func(*corev1.Pod) bool) ([]workload, error) {
+	pods, err := linkerd.KubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[workload]bool{}
+	workloads := []workload{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || !filter(pod) {
+			continue
+		}
+
+		w, ok, err := linkerd.podOwner(pod)
+		if err != nil {
+			return nil, err
+		}
+		if ok && !seen[w] {
+			seen[w] = true
+			workloads = append(workloads, w)
+		}
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		return workloads[i].String() < workloads[j].String()
+	})
+	return workloads, nil
+}
+
+// restartWorkloads restarts all the workloads and waits for them to be ready
+func (linkerd *Linkerd) restartWorkloads(workloads []workload, timeout time.Duration) error {
+	for _, w := range workloads {
+		if err := linkerd.restartWorkload(w); err != nil {
+			return err
+		}
+	}
+	for _, w := range workloads {
+		if err := linkerd.waitForWorkload(w, timeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// workloadPods returns the pods of the workload which are not terminating
+func (linkerd *Linkerd) workloadPods(w workload) ([]corev1.Pod, error) {
+	selector, err := linkerd.workloadSelector(w)
+	if err != nil {
+		return nil, err
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := linkerd.KubeClient.CoreV1().Pods(w.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: s.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	pods := []corev1.Pod{}
+	for _, pod := range list.Items {
+		if pod.DeletionTimestamp == nil {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// workloadSelector returns the selector of the pods of the workload
+func (linkerd *Linkerd) workloadSelector(w workload) (*metav1.LabelSelector, error) {
+	var selector *metav1.LabelSelector
+	apps := linkerd.KubeClient.AppsV1()
+	switch w.Kind {
+	case deploymentKind:
+		d, err := apps.Deployments(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = d.Spec.Selector
+	case statefulSetKind:
+		s, err := apps.StatefulSets(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = s.Spec.Selector
+	case daemonSetKind:
+		ds, err := apps.DaemonSets(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = ds.Spec.Selector
+	case jobKind:
+		j, err := linkerd.KubeClient.BatchV1().Jobs(w.Namespace).Get(context.TODO(), w.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		selector = j.Spec.Selector
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %s", w.Kind)
+	}
+	return selector, nil
+}